@@ -0,0 +1,54 @@
+// Command grpcserver exposes pkg/service over Envoy's RateLimit v3 gRPC API
+// so Envoy/Istio sidecars can use this module as their external rate-limit
+// service.
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+	"time"
+
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc"
+
+	"ratelimiter-app/pkg/ratelimitgrpc"
+	"ratelimiter-app/pkg/service"
+)
+
+func main() {
+	configPath := os.Getenv("RATELIMIT_CONFIG")
+	if configPath == "" {
+		configPath = "ratelimit-config.yaml"
+	}
+
+	config, err := ratelimitgrpc.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("loading ratelimit config %s: %v", configPath, err)
+	}
+
+	stopCh := make(chan struct{})
+	config.WatchSIGHUP(stopCh)
+	defer close(stopCh)
+
+	server := ratelimitgrpc.NewServer(config, func(window time.Duration) *service.Service {
+		return service.NewService(service.SlidingWindow, service.WithWindow(window))
+	})
+
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = ":8081"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("listening on %s: %v", addr, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(grpcServer, server)
+
+	log.Println("Starting Envoy RateLimit gRPC server on", addr)
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatalf("grpc server: %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 
+	"ratelimiter-app/internal/auth"
 	"ratelimiter-app/internal/handler"
 	"ratelimiter-app/pkg/service"
 )
@@ -14,8 +16,17 @@ func main() {
 	svc.SetLimit("apikey-abc", 100) // Per-API-key limit
 	// svc.limit = 5 // global default, already set in NewService
 
+	verifier := auth.NewVerifier(auth.Config{
+		HMACSecret: []byte(os.Getenv("JWT_HMAC_SECRET")),
+		JWKSURL:    os.Getenv("JWT_JWKS_URL"),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		KeyClaims:  []string{"tenant_id", "azp", "sub"},
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+	})
+
 	h := handler.NewHandler(svc)
-	h.RegisterRoutes()
+	h.RegisterRoutes(verifier, nil)
 
 	log.Println("Starting server on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const (
+	claimsContextKey       contextKey = "auth.claims"
+	rateLimitKeyContextKey contextKey = "auth.rateLimitKey"
+	apiKeyLimitContextKey  contextKey = "auth.apiKeyLimit"
+)
+
+// ClaimsFromContext returns the JWT claims attached by Middleware, if the
+// request was authenticated via a bearer token rather than an API key.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(Claims)
+	return claims, ok
+}
+
+// KeyFromContext returns the rate-limit key Middleware resolved, whether
+// from a JWT claim or an API key.
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(rateLimitKeyContextKey).(string)
+	return key, ok
+}
+
+// APIKeyLimitFromContext returns the limit override Middleware resolved for
+// an API-key request, if its apiKeyLimits lookup had one configured.
+func APIKeyLimitFromContext(ctx context.Context) (int, bool) {
+	limit, ok := ctx.Value(apiKeyLimitContextKey).(int)
+	return limit, ok
+}
+
+// Middleware authenticates incoming requests and attaches the resolved
+// rate-limit key (and, for JWTs, the verified claims) to the request
+// context for downstream handlers.
+//
+// Requests carrying an X-Api-Key header are rate-limited by that key
+// directly, looked up via apiKeyLimits for a per-key override; pass nil if
+// no override source is configured. Otherwise the request must carry a
+// verifiable Bearer JWT, and the key is resolved via v.ResolveKey.
+func (v *Verifier) Middleware(apiKeyLimits func(apiKey string) (limit int, ok bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get("X-Api-Key"); apiKey != "" {
+				ctx := context.WithValue(r.Context(), rateLimitKeyContextKey, apiKey)
+				if apiKeyLimits != nil {
+					if limit, ok := apiKeyLimits(apiKey); ok {
+						ctx = context.WithValue(ctx, apiKeyLimitContextKey, limit)
+					}
+				}
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token, ok := bearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				writeUnauthorized(w, "invalid_request", "missing bearer token")
+				return
+			}
+			claims, err := v.Verify(r.Context(), token)
+			if err != nil {
+				writeUnauthorized(w, "invalid_token", err.Error())
+				return
+			}
+			key, ok := v.ResolveKey(claims)
+			if !ok {
+				writeUnauthorized(w, "invalid_token", "no configured rate-limit claim present")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+			ctx = context.WithValue(ctx, rateLimitKeyContextKey, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AdminMiddleware gates admin endpoints (limit/burst overrides) behind
+// v.cfg.AdminToken: unlike Middleware, it doesn't resolve a caller-scoped
+// rate-limit key, since admin requests act on a key of the caller's
+// choosing rather than their own - a valid end-user JWT or API key proves
+// nothing about whether they're allowed to change someone else's limit.
+func (v *Verifier) AdminMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r.Header.Get("Authorization"))
+			if !ok || v.cfg.AdminToken == "" ||
+				subtle.ConstantTimeCompare([]byte(token), []byte(v.cfg.AdminToken)) != 1 {
+				writeUnauthorized(w, "invalid_token", "missing or invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(authHeader string) (string, bool) {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return "", false
+	}
+	token := strings.TrimPrefix(authHeader, prefix)
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// writeUnauthorized rejects the request per RFC 6750 section 3.
+func writeUnauthorized(w http.ResponseWriter, errCode, description string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error=%q, error_description=%q`, errCode, description))
+	http.Error(w, description, http.StatusUnauthorized)
+}
@@ -1,12 +1,12 @@
 package handler
 
 import (
-	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"net/http"
+	"strconv"
+
+	"ratelimiter-app/internal/auth"
 	"ratelimiter-app/pkg/service"
-	"strings"
 )
 
 type Handler struct {
@@ -18,55 +18,94 @@ func NewHandler(s *service.Service) *Handler {
 }
 
 func (h *Handler) Acquire(w http.ResponseWriter, r *http.Request) {
-	auth := r.Header.Get("Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		http.Error(w, "missing or invalid Authorization header", http.StatusUnauthorized)
-		return
-	}
-	token := strings.TrimPrefix(auth, "Bearer ")
-	claims, err := parseJWT(token)
-	if err != nil {
-		http.Error(w, "invalid JWT", http.StatusUnauthorized)
+	ctx := r.Context()
+	key, ok := auth.KeyFromContext(ctx)
+	if !ok {
+		http.Error(w, "missing rate-limit key", http.StatusUnauthorized)
 		return
 	}
-	// Use "sub" (subject) or another claim as the key
-	key, ok := claims["sub"].(string)
-	if !ok || key == "" {
-		http.Error(w, "JWT missing sub claim", http.StatusUnauthorized)
-		return
+	if limit, ok := auth.APIKeyLimitFromContext(ctx); ok {
+		h.service.SetLimitKeepingBurst(key, limit)
 	}
 
-	ctx := r.Context()
 	input := map[string]interface{}{"key": key}
 	result := h.service.Acquire(ctx, input)
+	if retryAfterMs, ok := result["retry_after_ms"].(int64); ok {
+		w.Header().Set("Retry-After", strconv.FormatInt((retryAfterMs+999)/1000, 10))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
-	auth := r.Header.Get("Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		http.Error(w, "missing or invalid Authorization header", http.StatusUnauthorized)
-		return
-	}
-	token := strings.TrimPrefix(auth, "Bearer ")
-	claims, err := parseJWT(token)
-	if err != nil {
-		http.Error(w, "invalid JWT", http.StatusUnauthorized)
-		return
-	}
-	key, ok := claims["sub"].(string)
-	if !ok || key == "" {
-		http.Error(w, "JWT missing sub claim", http.StatusUnauthorized)
+	ctx := r.Context()
+	key, ok := auth.KeyFromContext(ctx)
+	if !ok {
+		http.Error(w, "missing rate-limit key", http.StatusUnauthorized)
 		return
 	}
 
-	ctx := r.Context()
 	result := h.service.Status(ctx, key)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// AdminConfig implements a REST view over a key's limit/burst override:
+// GET reads it, PUT sets it, DELETE removes it. Writes go through
+// Service.EnqueueSetLimit/EnqueueDeleteLimit rather than mutating the
+// Service directly, so that with a shared configQueue configured, every
+// instance in the deployment converges on the same change.
+func (h *Handler) AdminConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "missing key", http.StatusBadRequest)
+			return
+		}
+		limit, burst, overridden := h.service.LimitConfig(key)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"key":        key,
+			"limit":      limit,
+			"burst":      burst,
+			"overridden": overridden,
+		})
+
+	case http.MethodPut:
+		var body struct {
+			Key   string `json:"key"`
+			Limit int    `json:"limit"`
+			Burst int    `json:"burst"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Key == "" || body.Limit <= 0 {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.EnqueueSetLimit(r.Context(), body.Key, body.Limit, body.Burst); err != nil {
+			http.Error(w, "config queue: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	case http.MethodDelete:
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "missing key", http.StatusBadRequest)
+			return
+		}
+		if err := h.service.EnqueueDeleteLimit(r.Context(), key); err != nil {
+			http.Error(w, "config queue: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 	w.Write([]byte(h.service.Metrics()))
@@ -118,27 +157,17 @@ func (h *Handler) AdminUI(w http.ResponseWriter, r *http.Request) {
 `))
 }
 
-// RegisterRoutes sets up the HTTP handlers using only net/http
-func (h *Handler) RegisterRoutes() {
-	http.HandleFunc("/api/acquire", h.Acquire)
-	http.HandleFunc("/api/status", h.Status)
+// RegisterRoutes sets up the HTTP handlers using only net/http. acquire and
+// status are wrapped in verifier's auth middleware so they only run once a
+// rate-limit key has been resolved from a verified JWT or an API key;
+// admin/config is wrapped in verifier's AdminMiddleware instead, since it
+// acts on a key of the caller's choosing rather than the caller's own.
+func (h *Handler) RegisterRoutes(verifier *auth.Verifier, apiKeyLimits func(apiKey string) (limit int, ok bool)) {
+	authenticated := verifier.Middleware(apiKeyLimits)
+	adminAuthenticated := verifier.AdminMiddleware()
+	http.Handle("/api/acquire", authenticated(http.HandlerFunc(h.Acquire)))
+	http.Handle("/api/status", authenticated(http.HandlerFunc(h.Status)))
 	http.HandleFunc("/metrics", h.Metrics)
-	http.HandleFunc("/admin", h.AdminUI) // <-- Add this line
-}
-
-func parseJWT(tokenString string) (map[string]interface{}, error) {
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
-		return nil, errors.New("invalid JWT format")
-	}
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return nil, err
-	}
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return nil, err
-	}
-	// NOTE: This does NOT verify the signature! For demo only.
-	return claims, nil
+	http.HandleFunc("/admin", h.AdminUI)
+	http.Handle("/admin/config", adminAuthenticated(http.HandlerFunc(h.AdminConfig)))
 }
@@ -0,0 +1,70 @@
+package adminqueue
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// SnapshotFunc returns the current config state in whatever shape the
+// caller wants persisted (typically a map of key -> limit/burst plus the
+// active algorithm).
+type SnapshotFunc func() interface{}
+
+// Compactor periodically asks snapshot for the full current config and
+// persists it via a Snapshotter, bounding how many ops a reader ever needs
+// to replay after a restart to the ones written since the last snapshot.
+type Compactor struct {
+	snapshotter Snapshotter
+	snapshot    SnapshotFunc
+	every       int
+	ticks       chan struct{}
+}
+
+// NewCompactor builds a Compactor that snapshots after every `every` calls
+// to Tick.
+func NewCompactor(snapshotter Snapshotter, every int, snapshot SnapshotFunc) *Compactor {
+	return &Compactor{
+		snapshotter: snapshotter,
+		snapshot:    snapshot,
+		every:       every,
+		ticks:       make(chan struct{}, 1024),
+	}
+}
+
+// Tick records that one op was applied. It never blocks; a tick dropped
+// because the buffer is full only delays the next snapshot slightly, which
+// is an acceptable trade for never stalling the caller applying ops.
+func (c *Compactor) Tick() {
+	select {
+	case c.ticks <- struct{}{}:
+	default:
+	}
+}
+
+// Run counts ticks and writes a snapshot every `every` of them, until ctx is
+// cancelled. Intended to run in its own goroutine for the lifetime of the
+// Service that owns this Compactor.
+func (c *Compactor) Run(ctx context.Context) {
+	count := 0
+	for {
+		select {
+		case <-c.ticks:
+			count++
+			if count < c.every {
+				continue
+			}
+			count = 0
+			data, err := json.Marshal(c.snapshot())
+			if err != nil {
+				log.Println("adminqueue: snapshot marshal failed:", err)
+				continue
+			}
+			if err := c.snapshotter.SaveSnapshot(ctx, data); err != nil {
+				log.Println("adminqueue: snapshot save failed:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
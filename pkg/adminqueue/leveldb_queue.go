@@ -0,0 +1,159 @@
+package adminqueue
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// opPrefix namespaces queued ops in the keyspace so snapshotKey can't
+// collide with a sequence number.
+var opPrefix = []byte("op:")
+
+// snapshotKey holds the single most recent Compactor snapshot.
+var snapshotKey = []byte("snapshot")
+
+// LevelDBQueue is an embedded, single-process Queue backed by
+// syndtr/goleveldb. Ops are keyed by a monotonically-increasing sequence
+// number, so iterating the keyspace in order replays them in arrival order;
+// Ack simply deletes the key. It durably survives a restart of its own
+// process, but - unlike RedisQueue - isn't shared across instances, so it
+// suits a single-instance deployment or a per-instance local buffer in front
+// of a shared RedisQueue.
+type LevelDBQueue struct {
+	db      *leveldb.DB
+	mu      sync.Mutex // guards nextSeq assignment and its matching Put, below
+	nextSeq uint64
+	notify  chan struct{}
+}
+
+// NewLevelDBQueue opens (creating if necessary) a LevelDB database at dir
+// and resumes sequence numbering after the highest op already stored there.
+func NewLevelDBQueue(dir string) (*LevelDBQueue, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := &LevelDBQueue{db: db, notify: make(chan struct{}, 1)}
+
+	iter := db.NewIterator(util.BytesPrefix(opPrefix), nil)
+	for iter.Next() {
+		if seq := seqFromKey(iter.Key()); seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *LevelDBQueue) Close() error {
+	return q.db.Close()
+}
+
+func (q *LevelDBQueue) Push(ctx context.Context, op Op) error {
+	q.mu.Lock()
+	op.Seq = q.nextSeq
+	q.nextSeq++
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		q.mu.Unlock()
+		return err
+	}
+	err = q.db.Put(seqKey(op.Seq), data, nil)
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *LevelDBQueue) Drain(ctx context.Context) ([]Op, error) {
+	var ops []Op
+	iter := q.db.NewIterator(util.BytesPrefix(opPrefix), nil)
+	for iter.Next() {
+		var op Op
+		if err := json.Unmarshal(iter.Value(), &op); err != nil {
+			iter.Release()
+			return ops, err
+		}
+		ops = append(ops, op)
+	}
+	iter.Release()
+	return ops, iter.Error()
+}
+
+// Next returns the oldest undelivered op, blocking on notify until one is
+// pushed (LevelDB has no native blocking read, so this is how Push wakes a
+// waiting Next instead of polling).
+func (q *LevelDBQueue) Next(ctx context.Context) (Op, error) {
+	for {
+		op, ok, err := q.oldest()
+		if err != nil {
+			return Op{}, err
+		}
+		if ok {
+			return op, nil
+		}
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return Op{}, ctx.Err()
+		}
+	}
+}
+
+func (q *LevelDBQueue) oldest() (Op, bool, error) {
+	iter := q.db.NewIterator(util.BytesPrefix(opPrefix), nil)
+	defer iter.Release()
+	if !iter.Next() {
+		return Op{}, false, iter.Error()
+	}
+	var op Op
+	if err := json.Unmarshal(iter.Value(), &op); err != nil {
+		return Op{}, false, err
+	}
+	return op, true, nil
+}
+
+func (q *LevelDBQueue) Ack(ctx context.Context, op Op) error {
+	return q.db.Delete(seqKey(op.Seq), nil)
+}
+
+func (q *LevelDBQueue) SaveSnapshot(ctx context.Context, data []byte) error {
+	return q.db.Put(snapshotKey, data, nil)
+}
+
+func (q *LevelDBQueue) LoadSnapshot(ctx context.Context) ([]byte, bool, error) {
+	data, err := q.db.Get(snapshotKey, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, len(opPrefix)+8)
+	copy(key, opPrefix)
+	binary.BigEndian.PutUint64(key[len(opPrefix):], seq)
+	return key
+}
+
+func seqFromKey(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(opPrefix):])
+}
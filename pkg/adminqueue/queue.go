@@ -0,0 +1,59 @@
+// Package adminqueue persists admin/config changes (limit edits, algorithm
+// switches, tenant onboarding) to an on-disk or shared queue, so a Service
+// restart doesn't lose pending config, and so a fleet of Service instances
+// converge on the same config instead of each only knowing about the writes
+// its own HTTP request happened to land on.
+package adminqueue
+
+import "context"
+
+// OpType names the kind of config change an Op carries.
+type OpType string
+
+const (
+	OpSetLimit      OpType = "set_limit"
+	OpDeleteLimit   OpType = "delete_limit"
+	OpSetAlgorithm  OpType = "set_algorithm"
+	OpOnboardTenant OpType = "onboard_tenant"
+)
+
+// Op is one durable config change. Every Queue implementation persists it
+// JSON-encoded, so Seq is the only field a backend needs to interpret
+// itself; everything else is opaque payload a Service applies.
+type Op struct {
+	Seq       uint64 `json:"seq"`
+	Type      OpType `json:"type"`
+	Key       string `json:"key,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	Burst     int    `json:"burst,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// Queue is a durable, ordered, at-least-once-delivered stream of Ops. A
+// writer (the admin HTTP API) calls Push; a reader (a Service) calls Drain
+// once at startup to replay everything already queued, then Next in a loop
+// to pick up new ops as they arrive, Ack-ing each once it's been applied.
+type Queue interface {
+	Push(ctx context.Context, op Op) error
+
+	// Drain returns every op currently queued, in arrival order, without
+	// blocking. Each returned op has already been moved to whatever
+	// in-flight state Ack expects, exactly like Next.
+	Drain(ctx context.Context) ([]Op, error)
+
+	// Next blocks until an op is available and returns it. The op is not
+	// considered delivered until Ack is called; a reader that crashes
+	// between Next and Ack will see the op again from Drain on restart.
+	Next(ctx context.Context) (Op, error)
+
+	// Ack marks op as fully applied, so it's not redelivered.
+	Ack(ctx context.Context, op Op) error
+}
+
+// Snapshotter is implemented by Queue backends that can also store a single
+// point-in-time snapshot of the full config, so a Compactor can bound how
+// far back a reader ever needs to replay from.
+type Snapshotter interface {
+	SaveSnapshot(ctx context.Context, data []byte) error
+	LoadSnapshot(ctx context.Context) (data []byte, ok bool, err error)
+}
@@ -0,0 +1,117 @@
+package adminqueue
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisQueue is a Queue shared across every Service instance pointed at the
+// same Redis, which is what lets a config change made against one instance
+// converge onto all of them. It follows the standard reliable-queue
+// pattern: Next moves an op from the main list to a processing list with
+// BRPOPLPUSH, and Ack removes it from there, so a reader that dies between
+// the two still has the op sitting in processing rather than having lost it,
+// and Drain hands it back on the next startup.
+type RedisQueue struct {
+	client     redis.UniversalClient
+	listKey    string
+	procKey    string
+	snapKey    string
+	nextSeqKey string
+}
+
+// NewRedisQueue builds a RedisQueue namespaced under name, so multiple
+// queues (e.g. one per tenant or per Service) can share a Redis instance.
+func NewRedisQueue(client redis.UniversalClient, name string) *RedisQueue {
+	return &RedisQueue{
+		client:     client,
+		listKey:    "adminqueue:{" + name + "}:ops",
+		procKey:    "adminqueue:{" + name + "}:processing",
+		snapKey:    "adminqueue:{" + name + "}:snapshot",
+		nextSeqKey: "adminqueue:{" + name + "}:seq",
+	}
+}
+
+func (q *RedisQueue) Push(ctx context.Context, op Op) error {
+	seq, err := q.client.Incr(ctx, q.nextSeqKey).Result()
+	if err != nil {
+		return err
+	}
+	op.Seq = uint64(seq)
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(ctx, q.listKey, data).Err()
+}
+
+// Drain returns, oldest first, the ops already in processing (delivered
+// earlier but never Acked), then moves every currently-queued op into
+// processing without blocking, for the one-time replay a Service does on
+// startup before switching to Next.
+func (q *RedisQueue) Drain(ctx context.Context) ([]Op, error) {
+	var ops []Op
+	inFlight, err := q.client.LRange(ctx, q.procKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(inFlight) - 1; i >= 0; i-- {
+		var op Op
+		if err := json.Unmarshal([]byte(inFlight[i]), &op); err != nil {
+			return ops, err
+		}
+		ops = append(ops, op)
+	}
+	for {
+		res, err := q.client.RPopLPush(ctx, q.listKey, q.procKey).Result()
+		if err == redis.Nil {
+			return ops, nil
+		}
+		if err != nil {
+			return ops, err
+		}
+		var op Op
+		if err := json.Unmarshal([]byte(res), &op); err != nil {
+			return ops, err
+		}
+		ops = append(ops, op)
+	}
+}
+
+func (q *RedisQueue) Next(ctx context.Context) (Op, error) {
+	res, err := q.client.BRPopLPush(ctx, q.listKey, q.procKey, 0).Result()
+	if err != nil {
+		return Op{}, err
+	}
+	var op Op
+	if err := json.Unmarshal([]byte(res), &op); err != nil {
+		return Op{}, err
+	}
+	return op, nil
+}
+
+func (q *RedisQueue) Ack(ctx context.Context, op Op) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return q.client.LRem(ctx, q.procKey, 1, data).Err()
+}
+
+func (q *RedisQueue) SaveSnapshot(ctx context.Context, data []byte) error {
+	return q.client.Set(ctx, q.snapKey, data, 0).Err()
+}
+
+func (q *RedisQueue) LoadSnapshot(ctx context.Context) ([]byte, bool, error) {
+	data, err := q.client.Get(ctx, q.snapKey).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
@@ -0,0 +1,15 @@
+// Package chiadapter re-exports pkg/middleware.Limit under a name that
+// reads naturally in a chi router's Use() call. chi middleware is just
+// func(http.Handler) http.Handler, so no actual adaptation is needed.
+package chiadapter
+
+import (
+	"net/http"
+
+	"ratelimiter-app/pkg/middleware"
+	"ratelimiter-app/pkg/service"
+)
+
+func Limit(svc *service.Service, keyFn func(*http.Request) string, opts ...middleware.Option) func(http.Handler) http.Handler {
+	return middleware.Limit(svc, keyFn, opts...)
+}
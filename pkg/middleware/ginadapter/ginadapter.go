@@ -0,0 +1,32 @@
+// Package ginadapter adapts pkg/middleware.Limit to gin.HandlerFunc.
+package ginadapter
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ratelimiter-app/pkg/middleware"
+	"ratelimiter-app/pkg/service"
+)
+
+// Limit returns a gin.HandlerFunc that enforces svc's rate limit the same
+// way middleware.Limit does for plain net/http, aborting the gin chain on
+// deny.
+func Limit(svc *service.Service, keyFn func(*http.Request) string, opts ...middleware.Option) gin.HandlerFunc {
+	limiter := middleware.Limit(svc, keyFn, opts...)
+
+	return func(c *gin.Context) {
+		allowed := false
+		handler := limiter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed = true
+		}))
+		handler.ServeHTTP(c.Writer, c.Request)
+
+		if !allowed {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"ratelimiter-app/internal/auth"
+)
+
+// IPKeyFunc extracts the client IP for rate-limiting. X-Forwarded-For and
+// X-Real-IP are only honored when the immediate peer's address is in
+// trustedProxies - otherwise a client could spoof either header to dodge
+// its own limit.
+func IPKeyFunc(trustedProxies ...string) func(*http.Request) string {
+	trusted := make(map[string]bool, len(trustedProxies))
+	for _, p := range trustedProxies {
+		trusted[p] = true
+	}
+	return func(r *http.Request) string {
+		peer := remoteIP(r)
+		if !trusted[peer] {
+			return peer
+		}
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+		if real := r.Header.Get("X-Real-IP"); real != "" {
+			return real
+		}
+		return peer
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// JWTClaimKeyFunc reads the rate-limit key internal/auth's Middleware
+// already resolved from the verified JWT and attached to the request
+// context.
+func JWTClaimKeyFunc() func(*http.Request) string {
+	return func(r *http.Request) string {
+		key, _ := auth.KeyFromContext(r.Context())
+		return key
+	}
+}
+
+// APIKeyFunc reads the rate-limit key from header, defaulting to X-Api-Key.
+func APIKeyFunc(header string) func(*http.Request) string {
+	if header == "" {
+		header = "X-Api-Key"
+	}
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// CompositeKeyFunc tries each extractor in order and returns the first
+// non-empty key.
+func CompositeKeyFunc(fns ...func(*http.Request) string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		for _, fn := range fns {
+			if key := fn(r); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,94 @@
+// Package middleware wraps pkg/service behind a standard
+// func(http.Handler) http.Handler so any Go HTTP server can protect
+// arbitrary routes, not just the explicit /api/acquire endpoint.
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"ratelimiter-app/pkg/service"
+)
+
+// Option customizes Limit.
+type Option func(*config)
+
+type config struct {
+	bypass   func(*http.Request) bool
+	jsonBody bool
+}
+
+// WithBypass skips the limiter entirely for requests where fn returns true,
+// e.g. health checks and admin routes.
+func WithBypass(fn func(*http.Request) bool) Option {
+	return func(c *config) { c.bypass = fn }
+}
+
+// WithJSONBody switches the 429 response body from plain text to a small
+// JSON object.
+func WithJSONBody() Option {
+	return func(c *config) { c.jsonBody = true }
+}
+
+// Limit wraps next so every request not skipped by WithBypass is first
+// checked against svc, keyed by keyFn(r). It emits the IETF draft
+// RateLimit-Limit/Remaining/Reset headers on every checked response, and on
+// deny responds 429 with Retry-After instead of calling next.
+func Limit(svc *service.Service, keyFn func(*http.Request) string, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.bypass != nil && cfg.bypass(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := keyFn(r)
+			ctx := r.Context()
+			result := svc.Acquire(ctx, map[string]interface{}{"key": key})
+			status := svc.Status(ctx, key)
+			writeRateLimitHeaders(w, status)
+
+			if allowed, _ := result["allowed"].(bool); !allowed {
+				writeDeny(w, cfg, result)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeRateLimitHeaders(w http.ResponseWriter, status map[string]interface{}) {
+	if limit, ok := status["limit"].(int); ok {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+	}
+	if remaining, ok := status["tokens_left"].(int); ok {
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	if windowSec, ok := status["window_sec"].(int); ok {
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(windowSec))
+	}
+}
+
+func writeDeny(w http.ResponseWriter, cfg config, result map[string]interface{}) {
+	retryAfterSec := int64(1)
+	if ms, ok := result["retry_after_ms"].(int64); ok && ms > 0 {
+		retryAfterSec = (ms + 999) / 1000
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSec, 10))
+
+	if cfg.jsonBody {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "rate limit exceeded"})
+		return
+	}
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte("rate limit exceeded"))
+}
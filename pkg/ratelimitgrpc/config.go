@@ -0,0 +1,129 @@
+// Package ratelimitgrpc exposes pkg/service over Envoy's RateLimit v3 gRPC
+// API, so this module can be dropped in as an external rate-limit service
+// for Envoy/Istio sidecars.
+package ratelimitgrpc
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unit is one of the window granularities Envoy's ratelimit config format
+// supports.
+type Unit string
+
+const (
+	UnitSecond Unit = "second"
+	UnitMinute Unit = "minute"
+	UnitHour   Unit = "hour"
+	UnitDay    Unit = "day"
+)
+
+func (u Unit) duration() time.Duration {
+	switch u {
+	case UnitMinute:
+		return time.Minute
+	case UnitHour:
+		return time.Hour
+	case UnitDay:
+		return 24 * time.Hour
+	default:
+		return time.Second
+	}
+}
+
+// Rule mirrors one entry of Envoy's ratelimit config format: a
+// (domain, descriptor_key, descriptor_value) tuple mapped to a
+// requests-per-unit rate.
+type Rule struct {
+	Domain          string `yaml:"domain"`
+	DescriptorKey   string `yaml:"descriptor_key"`
+	DescriptorValue string `yaml:"descriptor_value"`
+	Unit            Unit   `yaml:"unit"`
+	RequestsPerUnit int    `yaml:"requests_per_unit"`
+}
+
+type configFile struct {
+	Descriptors []Rule `yaml:"descriptors"`
+}
+
+// Config is the live, hot-reloadable set of Rules a Server evaluates
+// descriptors against.
+type Config struct {
+	path string
+
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// LoadConfig reads and parses the YAML ratelimit config at path.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-reads the config file from disk, atomically replacing the rule
+// set. Callers wire this to SIGHUP via WatchSIGHUP.
+func (c *Config) Reload() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+	var raw configFile
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	rules := make(map[string]Rule, len(raw.Descriptors))
+	for _, r := range raw.Descriptors {
+		rules[ruleKey(r.Domain, r.DescriptorKey, r.DescriptorValue)] = r
+	}
+
+	c.mu.Lock()
+	c.rules = rules
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Config) lookup(domain, key, value string) (Rule, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r, ok := c.rules[ruleKey(domain, key, value)]
+	return r, ok
+}
+
+func ruleKey(domain, key, value string) string {
+	return domain + "|" + key + "|" + value
+}
+
+// WatchSIGHUP reloads the config on every SIGHUP, mirroring how Envoy's own
+// ratelimit deployments are typically reloaded without a restart. It runs
+// until stopCh is closed.
+func (c *Config) WatchSIGHUP(stopCh <-chan struct{}) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-sighup:
+				if err := c.Reload(); err != nil {
+					log.Println("ratelimitgrpc: config reload failed:", err)
+					continue
+				}
+				log.Println("ratelimitgrpc: config reloaded")
+			case <-stopCh:
+				signal.Stop(sighup)
+				return
+			}
+		}
+	}()
+}
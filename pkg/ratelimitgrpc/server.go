@@ -0,0 +1,115 @@
+package ratelimitgrpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+
+	"ratelimiter-app/pkg/service"
+)
+
+// Server implements envoy.service.ratelimit.v3.RateLimitService. It keeps
+// one pkg/service.Service per Unit, since each Service has a single
+// rate-limit window but descriptors arriving from Envoy can be configured
+// against any of the four Envoy units.
+type Server struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+
+	config   *Config
+	services map[Unit]*service.Service
+}
+
+// NewServer builds a Server backed by config, constructing one Service per
+// Unit via newService (so callers can choose algorithm, storage backend,
+// etc. while this package supplies the per-unit window).
+func NewServer(config *Config, newService func(window time.Duration) *service.Service) *Server {
+	s := &Server{config: config, services: make(map[Unit]*service.Service, 4)}
+	for _, u := range []Unit{UnitSecond, UnitMinute, UnitHour, UnitDay} {
+		s.services[u] = newService(u.duration())
+	}
+	return s
+}
+
+func (s *Server) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	resp := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+
+	for _, d := range req.GetDescriptors() {
+		status, headers := s.evaluate(ctx, req.GetDomain(), d)
+		resp.Statuses = append(resp.Statuses, status)
+		resp.ResponseHeadersToAdd = append(resp.ResponseHeadersToAdd, headers...)
+		if status.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			resp.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) evaluate(ctx context.Context, domain string, d *ratelimitv3.RateLimitDescriptor) (*rlsv3.RateLimitResponse_DescriptorStatus, []*corev3.HeaderValue) {
+	entries := d.GetEntries()
+	key := descriptorKey(domain, entries)
+
+	var rule Rule
+	found := false
+	for _, e := range entries {
+		if r, ok := s.config.lookup(domain, e.GetKey(), e.GetValue()); ok {
+			rule, found = r, true
+			break
+		}
+	}
+	if !found {
+		// No configured rule for this descriptor: Envoy's convention is to
+		// let it through rather than reject traffic it was never told to limit.
+		return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}, nil
+	}
+
+	svc := s.services[rule.Unit]
+	if svc == nil {
+		svc = s.services[UnitSecond]
+	}
+	svc.SetLimitKeepingBurst(key, rule.RequestsPerUnit)
+
+	hitsAddend := 1
+	if ha := d.GetHitsAddend(); ha != nil && ha.GetValue() > 0 {
+		hitsAddend = int(ha.GetValue())
+	}
+
+	result := svc.Acquire(ctx, map[string]interface{}{"key": key}, hitsAddend)
+	allowed, _ := result["allowed"].(bool)
+	code := rlsv3.RateLimitResponse_OK
+	if !allowed {
+		code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+
+	status := svc.Status(ctx, key)
+	headers := rateLimitHeaders(rule.RequestsPerUnit, status)
+	return &rlsv3.RateLimitResponse_DescriptorStatus{Code: code}, headers
+}
+
+func rateLimitHeaders(limit int, status map[string]interface{}) []*corev3.HeaderValue {
+	remaining, _ := status["tokens_left"].(int)
+	headers := []*corev3.HeaderValue{
+		{Key: "X-RateLimit-Limit", Value: strconv.Itoa(limit)},
+		{Key: "X-RateLimit-Remaining", Value: strconv.Itoa(remaining)},
+	}
+	if windowSec, ok := status["window_sec"].(int); ok {
+		headers = append(headers, &corev3.HeaderValue{Key: "X-RateLimit-Reset", Value: strconv.Itoa(windowSec)})
+	}
+	return headers
+}
+
+// descriptorKey builds the Storage key for a descriptor: the domain plus
+// each entry's "key=value" pair, joined so distinct descriptors never
+// collide on the same counter.
+func descriptorKey(domain string, entries []*ratelimitv3.RateLimitDescriptor_Entry) string {
+	parts := make([]string, 0, len(entries)+1)
+	parts = append(parts, domain)
+	for _, e := range entries {
+		parts = append(parts, e.GetKey()+"="+e.GetValue())
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,173 @@
+package ratelimitgrpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"ratelimiter-app/pkg/service"
+)
+
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ratelimit.yaml")
+	contents := `
+descriptors:
+  - domain: example
+    descriptor_key: user_id
+    descriptor_value: user1
+    unit: minute
+    requests_per_unit: 1
+`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+func descriptor(key, value string) *ratelimitv3.RateLimitDescriptor {
+	return &ratelimitv3.RateLimitDescriptor{
+		Entries: []*ratelimitv3.RateLimitDescriptor_Entry{
+			{Key: key, Value: value},
+		},
+	}
+}
+
+func descriptorWithHitsAddend(key, value string, hitsAddend uint64) *ratelimitv3.RateLimitDescriptor {
+	d := descriptor(key, value)
+	d.HitsAddend = wrapperspb.UInt64(hitsAddend)
+	return d
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	return NewServer(testConfig(t), func(window time.Duration) *service.Service {
+		return service.NewService(service.TokenBucket,
+			service.WithStorageBackend(service.StorageBackendMemory),
+			service.WithWindow(window),
+		)
+	})
+}
+
+func TestShouldRateLimit_AllowsThenLimits(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	req := &rlsv3.RateLimitRequest{
+		Domain:      "example",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("user_id", "user1")},
+	}
+
+	resp, err := s.ShouldRateLimit(ctx, req)
+	if err != nil {
+		t.Fatalf("ShouldRateLimit: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("first request: expected OK, got %v", resp.OverallCode)
+	}
+	if len(resp.Statuses) != 1 || resp.Statuses[0].Code != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("first request: unexpected statuses %v", resp.Statuses)
+	}
+
+	resp, err = s.ShouldRateLimit(ctx, req)
+	if err != nil {
+		t.Fatalf("ShouldRateLimit: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OVER_LIMIT {
+		t.Fatalf("second request: expected OVER_LIMIT, got %v", resp.OverallCode)
+	}
+}
+
+// TestShouldRateLimit_HitsAddendAppliedInOneAcquire verifies a descriptor's
+// hits_addend is honored as a single Acquire call consuming that many units,
+// rather than one Acquire per unit: a hits_addend that alone exceeds the
+// remaining quota must be reported OVER_LIMIT.
+func TestShouldRateLimit_HitsAddendAppliedInOneAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.yaml")
+	contents := `
+descriptors:
+  - domain: example
+    descriptor_key: user_id
+    descriptor_value: user1
+    unit: minute
+    requests_per_unit: 5
+`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	s := NewServer(cfg, func(window time.Duration) *service.Service {
+		return service.NewService(service.TokenBucket,
+			service.WithStorageBackend(service.StorageBackendMemory),
+			service.WithWindow(window),
+		)
+	})
+	ctx := context.Background()
+
+	resp, err := s.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+		Domain:      "example",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptorWithHitsAddend("user_id", "user1", 3)},
+	})
+	if err != nil {
+		t.Fatalf("ShouldRateLimit: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("first request (hits_addend=3, limit=5): expected OK, got %v", resp.OverallCode)
+	}
+	if remaining := headerValue(resp.ResponseHeadersToAdd, "X-RateLimit-Remaining"); remaining != "2" {
+		t.Fatalf("expected 2 remaining after admitting 3 of 5, got %q", remaining)
+	}
+
+	resp, err = s.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+		Domain:      "example",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptorWithHitsAddend("user_id", "user1", 3)},
+	})
+	if err != nil {
+		t.Fatalf("ShouldRateLimit: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OVER_LIMIT {
+		t.Fatalf("second request (hits_addend=3, only 2 left): expected OVER_LIMIT, got %v", resp.OverallCode)
+	}
+}
+
+func headerValue(headers []*corev3.HeaderValue, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func TestShouldRateLimit_UnconfiguredDescriptorPassesThrough(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	req := &rlsv3.RateLimitRequest{
+		Domain:      "example",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("user_id", "unknown-user")},
+	}
+
+	resp, err := s.ShouldRateLimit(ctx, req)
+	if err != nil {
+		t.Fatalf("ShouldRateLimit: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("expected OK for unconfigured descriptor, got %v", resp.OverallCode)
+	}
+	if len(resp.ResponseHeadersToAdd) != 0 {
+		t.Fatalf("expected no rate-limit headers for unconfigured descriptor, got %v", resp.ResponseHeadersToAdd)
+	}
+}
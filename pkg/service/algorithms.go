@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitAlgorithm selects how a Service decides whether to admit a
+// request. TokenBucket and SlidingWindow both run straight off the
+// configured Storage, so they're interchangeable names for the same
+// trim/count/admit behavior; FixedWindow bucketizes keys before doing the
+// same. GCRA keeps its own per-key state (a single theoretical arrival
+// time) because that doesn't fit the counter-shaped Storage interface; it
+// replaced this package's original LeakyBucket implementation, which kept
+// an unbounded per-key slice of call timestamps with no burst allowance and
+// no Storage backing at all.
+type RateLimitAlgorithm int
+
+const (
+	TokenBucket RateLimitAlgorithm = iota
+	SlidingWindow
+	FixedWindow
+	GCRA
+)
+
+// String names alg the way algorithmFromString expects back, so a value can
+// round-trip through a config snapshot or an adminqueue.Op.
+func (alg RateLimitAlgorithm) String() string {
+	switch alg {
+	case SlidingWindow:
+		return "sliding_window"
+	case FixedWindow:
+		return "fixed_window"
+	case GCRA:
+		return "gcra"
+	default:
+		return "token_bucket"
+	}
+}
+
+// algorithmFromString parses the names RateLimitAlgorithm.String() produces,
+// e.g. from an adminqueue.OpSetAlgorithm op.
+func algorithmFromString(name string) (RateLimitAlgorithm, bool) {
+	switch name {
+	case "token_bucket":
+		return TokenBucket, true
+	case "sliding_window":
+		return SlidingWindow, true
+	case "fixed_window":
+		return FixedWindow, true
+	case "gcra":
+		return GCRA, true
+	default:
+		return 0, false
+	}
+}
+
+// fixedWindowKey buckets key into the window currently in effect, so that a
+// sliding-window Storage backend behaves like a fixed window: all requests
+// within the same bucket share one counter that resets at the boundary.
+func fixedWindowKey(key string, window time.Duration) string {
+	bucket := time.Now().UnixNano() / window.Nanoseconds()
+	return key + ":fw:" + strconv.FormatInt(bucket, 10)
+}
+
+func (s *Service) acquireViaStorage(ctx context.Context, key string, limit, hits int) map[string]interface{} {
+	dec, err := acquireHits(ctx, s.storage, key, hits, limit, s.window)
+	if err != nil {
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed": false,
+			"error":   "storage error",
+		}
+	}
+	if !dec.Allowed {
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed":        false,
+			"error":          "rate limit exceeded",
+			"retry_after_ms": dec.RetryAfter.Milliseconds(),
+		}
+	}
+	atomic.AddInt64(&s.successfulAcquires, 1)
+	return map[string]interface{}{"allowed": true}
+}
+
+func (s *Service) statusViaStorage(ctx context.Context, key string, limit int) map[string]interface{} {
+	st, err := s.storage.Status(ctx, key, limit, s.window)
+	tokensLeft := limit
+	if err == nil {
+		tokensLeft = limit - st.Count
+		if tokensLeft < 0 {
+			tokensLeft = 0
+		}
+	}
+	result := map[string]interface{}{
+		"key":         key,
+		"tokens_left": tokensLeft,
+		"limit":       limit,
+		"window_sec":  int(s.window.Seconds()),
+		"refill_rate": float64(limit) / s.window.Seconds(),
+	}
+	if s.useRedis {
+		result["source"] = "redis"
+	}
+	return result
+}
+
+// gcraParams is the pair of durations GCRA needs to decide and explain an
+// admission: how often one request is allowed at the steady rate, and how
+// far a key may get ahead of that rate (its burst) before it's throttled
+// back.
+//
+//	emission_interval = window / limit
+//	delay_tolerance   = emission_interval * burst
+func (s *Service) gcraParams(key string, limit int) (emissionInterval, delayTolerance time.Duration) {
+	emissionInterval = s.window / time.Duration(limit)
+	burst := s.getBurstForKey(key)
+	delayTolerance = emissionInterval * time.Duration(burst)
+	return emissionInterval, delayTolerance
+}
+
+// acquireGCRA implements the Generic Cell Rate Algorithm: each key's only
+// state is a single TAT (theoretical arrival time). An arrival at time now
+// advances the TAT by emission_interval (or by hits * emission_interval, to
+// admit hits units in the same decision); it's admitted as long as the
+// resulting TAT doesn't land further than delay_tolerance past now, which is
+// what lets a key burst up to burst requests before being held to the
+// steady limit/window rate.
+func (s *Service) acquireGCRA(ctx context.Context, key string, hits int) map[string]interface{} {
+	limit := s.getLimitForKey(key)
+	emissionInterval, delayTolerance := s.gcraParams(key, limit)
+
+	if s.useRedis && s.gcraScript != nil {
+		return s.acquireGCRARedis(ctx, key, emissionInterval, delayTolerance, hits)
+	}
+	return s.acquireGCRAMemory(key, emissionInterval, delayTolerance, hits)
+}
+
+// acquireGCRAMemory stores TAT as nanoseconds-since-epoch in a single *int64
+// per key, updated with a compare-and-swap loop instead of a mutex: a denied
+// request never has to wait for the winner of a race to release a lock, it
+// just reads the loser's retry_after off the same arithmetic.
+func (s *Service) acquireGCRAMemory(key string, emissionInterval, delayTolerance time.Duration, hits int) map[string]interface{} {
+	val, _ := s.gcraStates.LoadOrStore(key, new(int64))
+	tatPtr := val.(*int64)
+
+	for {
+		now := time.Now().UnixNano()
+		oldTAT := atomic.LoadInt64(tatPtr)
+		tat := oldTAT
+		if tat < now {
+			tat = now
+		}
+		newTAT := tat + emissionInterval.Nanoseconds()*int64(hits)
+
+		if newTAT-now <= delayTolerance.Nanoseconds() {
+			if !atomic.CompareAndSwapInt64(tatPtr, oldTAT, newTAT) {
+				continue // lost the race to a concurrent acquire; retry with a fresh read
+			}
+			atomic.AddInt64(&s.successfulAcquires, 1)
+			return map[string]interface{}{"allowed": true}
+		}
+
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed":        false,
+			"error":          "rate limit exceeded",
+			"retry_after_ms": (time.Duration(newTAT-now) - delayTolerance).Milliseconds(),
+		}
+	}
+}
+
+// acquireGCRARedis runs the equivalent of acquireGCRAMemory's CAS loop as a
+// single Lua script so concurrent instances share one TAT per key instead of
+// racing on a read-then-write pair.
+func (s *Service) acquireGCRARedis(ctx context.Context, key string, emissionInterval, delayTolerance time.Duration, hits int) map[string]interface{} {
+	res, err := s.gcraScript.run(ctx, []string{gcraKey(key)},
+		time.Now().UnixNano(), emissionInterval.Nanoseconds(), delayTolerance.Nanoseconds(), hits)
+	if err != nil {
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed": false,
+			"error":   "storage error",
+		}
+	}
+	allowed, retryAfterMs, err := parseGCRAResult(res)
+	if err != nil {
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed": false,
+			"error":   "storage error",
+		}
+	}
+	if !allowed {
+		atomic.AddInt64(&s.failedAcquires, 1)
+		return map[string]interface{}{
+			"allowed":        false,
+			"error":          "rate limit exceeded",
+			"retry_after_ms": retryAfterMs,
+		}
+	}
+	atomic.AddInt64(&s.successfulAcquires, 1)
+	return map[string]interface{}{"allowed": true}
+}
+
+func (s *Service) statusGCRA(ctx context.Context, key string, limit int) map[string]interface{} {
+	burst := s.getBurstForKey(key)
+	result := map[string]interface{}{
+		"key":         key,
+		"limit":       limit,
+		"window_sec":  int(s.window.Seconds()),
+		"refill_rate": float64(limit) / s.window.Seconds(),
+	}
+	if s.useRedis {
+		result["source"] = "redis"
+	}
+
+	emissionInterval, _ := s.gcraParams(key, limit)
+	var tatNanos int64
+	if s.useRedis && s.gcraScript != nil {
+		raw, err := s.redisClient.Get(ctx, gcraKey(key)).Int64()
+		if err != nil {
+			result["tokens_left"] = burst
+			return result
+		}
+		tatNanos = raw
+	} else {
+		val, ok := s.gcraStates.Load(key)
+		if !ok {
+			result["tokens_left"] = burst
+			return result
+		}
+		tatNanos = atomic.LoadInt64(val.(*int64))
+	}
+
+	now := time.Now().UnixNano()
+	if tatNanos < now {
+		result["tokens_left"] = burst
+		return result
+	}
+	used := int((tatNanos-now)/emissionInterval.Nanoseconds()) + 1
+	tokensLeft := burst - used
+	if tokensLeft < 0 {
+		tokensLeft = 0
+	}
+	result["tokens_left"] = tokensLeft
+	return result
+}
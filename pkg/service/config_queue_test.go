@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ratelimiter-app/pkg/adminqueue"
+)
+
+// fakeSnapshotQueue is a minimal in-memory adminqueue.Queue + Snapshotter:
+// just enough to drive startConfigQueue's snapshot-then-drain sequence
+// without a real LevelDB/Redis backend.
+type fakeSnapshotQueue struct {
+	snapshot []byte
+	hasSnap  bool
+}
+
+func (q *fakeSnapshotQueue) Push(ctx context.Context, op adminqueue.Op) error { return nil }
+func (q *fakeSnapshotQueue) Drain(ctx context.Context) ([]adminqueue.Op, error) {
+	return nil, nil
+}
+func (q *fakeSnapshotQueue) Next(ctx context.Context) (adminqueue.Op, error) {
+	<-ctx.Done()
+	return adminqueue.Op{}, ctx.Err()
+}
+func (q *fakeSnapshotQueue) Ack(ctx context.Context, op adminqueue.Op) error { return nil }
+
+func (q *fakeSnapshotQueue) SaveSnapshot(ctx context.Context, data []byte) error {
+	q.snapshot, q.hasSnap = data, true
+	return nil
+}
+func (q *fakeSnapshotQueue) LoadSnapshot(ctx context.Context) ([]byte, bool, error) {
+	return q.snapshot, q.hasSnap, nil
+}
+
+// TestNewService_LoadsConfigSnapshotOnStartup verifies that a Service
+// constructed against a queue holding a config snapshot restores its
+// limits, bursts and algorithm from it, without any ops left to Drain - i.e.
+// the snapshot, not the (already-acked, now-empty) queue, is what survives a
+// restart.
+func TestNewService_LoadsConfigSnapshotOnStartup(t *testing.T) {
+	q := &fakeSnapshotQueue{}
+
+	data, err := json.Marshal(configSnapshotData{
+		Limits:    map[string]int{"user1": 42},
+		Bursts:    map[string]int{"user1": 50},
+		Algorithm: GCRA.String(),
+	})
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	q.snapshot, q.hasSnap = data, true
+
+	svc := NewService(TokenBucket,
+		WithStorageBackend(StorageBackendMemory),
+		WithConfigQueue(q),
+	)
+
+	limit, burst, overridden := svc.LimitConfig("user1")
+	if !overridden {
+		t.Fatalf("expected user1 to have an override restored from the snapshot")
+	}
+	if limit != 42 || burst != 50 {
+		t.Fatalf("expected limit=42 burst=50, got limit=%d burst=%d", limit, burst)
+	}
+	if svc.algorithm != GCRA {
+		t.Fatalf("expected algorithm restored to GCRA, got %v", svc.algorithm)
+	}
+}
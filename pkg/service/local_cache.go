@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// cacheEntry is the locally cached view of a key's last known state in the
+// wrapped Storage.
+type cacheEntry struct {
+	mu                 sync.Mutex
+	count              int
+	limit              int
+	resetAt            time.Time // when the wrapped Storage expects the window to clear
+	expiresAt          time.Time // when this entry itself goes stale
+	hitsSinceReconcile int
+}
+
+// reconcileEvery bounds how many optimistic local increments a cached entry
+// absorbs before CachedStorage re-checks the wrapped Storage, so a burst that
+// outlives the cache's own count can't admit more than it should.
+const reconcileEvery = 20
+
+// CachedStorage sits in front of another Storage (typically RedisStorage)
+// and short-circuits Acquire once a key's quota is known, from a recent
+// response, to be exhausted - avoiding a round-trip per request in a hot-key
+// burst. Allow decisions optimistically bump the cached count and
+// periodically reconcile with the wrapped Storage.
+type CachedStorage struct {
+	next  Storage
+	ttl   time.Duration
+	cache *lru.Cache[string, *cacheEntry]
+
+	hits   int64
+	misses int64
+}
+
+// NewCachedStorage wraps next with an LRU of at most size entries, each kept
+// for at most ttl (further bounded per-key by how soon its window resets).
+func NewCachedStorage(next Storage, size int, ttl time.Duration) *CachedStorage {
+	cache, _ := lru.New[string, *cacheEntry](size)
+	return &CachedStorage{next: next, ttl: ttl, cache: cache}
+}
+
+func (c *CachedStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	now := time.Now()
+
+	if entry, ok := c.cache.Get(key); ok {
+		entry.mu.Lock()
+		if now.Before(entry.expiresAt) {
+			if entry.count >= entry.limit && now.Before(entry.resetAt) {
+				retryAfter := entry.resetAt.Sub(now)
+				entry.mu.Unlock()
+				atomic.AddInt64(&c.hits, 1)
+				return Decision{Allowed: false, Count: entry.count, RetryAfter: retryAfter}, nil
+			}
+
+			entry.count++
+			entry.hitsSinceReconcile++
+			hits := entry.hitsSinceReconcile
+			stale := hits >= reconcileEvery || !now.Before(entry.resetAt)
+			count := entry.count
+			entry.mu.Unlock()
+			atomic.AddInt64(&c.hits, 1)
+
+			if stale {
+				return c.reconcile(ctx, key, limit, window, hits)
+			}
+			return Decision{Allowed: true, Count: count}, nil
+		}
+		entry.mu.Unlock()
+	}
+
+	atomic.AddInt64(&c.misses, 1)
+	return c.reconcile(ctx, key, limit, window, 1)
+}
+
+func (c *CachedStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	return c.next.Status(ctx, key, limit, window)
+}
+
+// reconcile pushes hits - the admits decided purely from the local cache
+// since the last reconcile, plus the current request - back to the wrapped
+// Storage, and refreshes the cached entry from its response. For hits > 1
+// this uses the wrapped Storage's BulkAcquirer if it implements one (a
+// single round trip, e.g. RedisStorage's pipelined Lua script); otherwise it
+// falls back to replaying each hit as its own Acquire call, so correctness
+// doesn't depend on the backend supporting a bulk path.
+func (c *CachedStorage) reconcile(ctx context.Context, key string, limit int, window time.Duration, hits int) (Decision, error) {
+	dec, err := acquireHits(ctx, c.next, key, hits, limit, window)
+	if err != nil {
+		return dec, err
+	}
+
+	now := time.Now()
+	resetAt := now.Add(window)
+	if !dec.Allowed {
+		resetAt = now.Add(dec.RetryAfter)
+	}
+	ttl := resetAt.Sub(now)
+	if ttl > c.ttl {
+		ttl = c.ttl
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	c.cache.Add(key, &cacheEntry{
+		count:     dec.Count,
+		limit:     limit,
+		resetAt:   resetAt,
+		expiresAt: now.Add(ttl),
+	})
+	return dec, nil
+}
+
+// hitRatio reports (hits, misses) for Service.Metrics().
+func (c *CachedStorage) hitRatio() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
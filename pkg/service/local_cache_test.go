@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStorage is a fake Storage with no BulkAcquirer, used to verify
+// CachedStorage.reconcile's sequential-replay fallback pushes every
+// optimistic local admit back to the wrapped Storage instead of discarding
+// them between reconciles.
+type countingStorage struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (s *countingStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	return Decision{Allowed: true, Count: s.count}, nil
+}
+
+func (s *countingStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return State{Count: s.count}, nil
+}
+
+func (s *countingStorage) acquires() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// TestCachedStorage_ReconcileReplaysEveryAdmit drives reconcileEvery+1
+// admits through a backend with no BulkAcquirer: every one of them must
+// eventually reach the wrapped Storage, not just one per reconcileEvery
+// batch.
+func TestCachedStorage_ReconcileReplaysEveryAdmit(t *testing.T) {
+	backend := &countingStorage{}
+	cache := NewCachedStorage(backend, 16, time.Minute)
+
+	ctx := context.Background()
+	const calls = reconcileEvery + 1 // lands exactly on a reconcile boundary
+	for i := 0; i < calls; i++ {
+		dec, err := cache.Acquire(ctx, "user1", 1000, time.Minute)
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		if !dec.Allowed {
+			t.Fatalf("call %d: expected allowed", i)
+		}
+	}
+
+	if got := backend.acquires(); got != calls {
+		t.Fatalf("expected all %d admits reconciled to the backend, got %d", calls, got)
+	}
+}
+
+// bulkStorage is a fake Storage + BulkAcquirer, used to verify
+// CachedStorage.reconcile prefers a single AcquireN call over replaying
+// hits one at a time when the wrapped Storage supports it.
+type bulkStorage struct {
+	mu          sync.Mutex
+	count       int
+	acquireCall int
+	bulkCalls   []int // hits argument of each AcquireN call
+}
+
+func (s *bulkStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acquireCall++
+	s.count++
+	return Decision{Allowed: true, Count: s.count}, nil
+}
+
+func (s *bulkStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return State{Count: s.count}, nil
+}
+
+func (s *bulkStorage) AcquireN(ctx context.Context, key string, hits, limit int, window time.Duration) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bulkCalls = append(s.bulkCalls, hits)
+	s.count += hits
+	return Decision{Allowed: s.count <= limit, Count: s.count}, nil
+}
+
+func TestCachedStorage_ReconcileUsesBulkAcquirerWhenAvailable(t *testing.T) {
+	backend := &bulkStorage{}
+	cache := NewCachedStorage(backend, 16, time.Minute)
+
+	ctx := context.Background()
+	const calls = reconcileEvery + 1
+	for i := 0; i < calls; i++ {
+		if _, err := cache.Acquire(ctx, "user1", 1000, time.Minute); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+
+	if backend.acquireCall != 1 {
+		t.Fatalf("expected exactly 1 plain Acquire call (the initial cache miss), got %d", backend.acquireCall)
+	}
+	if len(backend.bulkCalls) != 1 || backend.bulkCalls[0] != reconcileEvery {
+		t.Fatalf("expected a single AcquireN call with hits=%d, got %v", reconcileEvery, backend.bulkCalls)
+	}
+	if backend.count != calls {
+		t.Fatalf("expected backend count to reflect all %d admits, got %d", calls, backend.count)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// MemcachedStorage implements Storage against Memcached's counter protocol:
+// Add seeds a zero-value counter with TTL=window (a no-op if it already
+// exists), then Increment bumps it atomically. Memcached has no sorted-set
+// equivalent to trim, so this behaves as a fixed window anchored to each
+// key's first request rather than a true sliding window.
+type MemcachedStorage struct {
+	client *memcache.Client
+}
+
+func NewMemcachedStorage(addrs ...string) *MemcachedStorage {
+	return &MemcachedStorage{client: memcache.New(addrs...)}
+}
+
+func memcachedAddrsFromEnv() []string {
+	addrs := os.Getenv("MEMCACHED_ADDRS")
+	if addrs == "" {
+		return []string{"localhost:11211"}
+	}
+	return strings.Split(addrs, ",")
+}
+
+func (m *MemcachedStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	err := m.client.Add(&memcache.Item{
+		Key:        key,
+		Value:      []byte("0"),
+		Expiration: int32(window.Seconds()),
+	})
+	if err != nil && err != memcache.ErrNotStored {
+		return Decision{}, err
+	}
+
+	count, err := m.client.Increment(key, 1)
+	if err != nil {
+		return Decision{}, err
+	}
+	if int(count) > limit {
+		return Decision{Allowed: false, Count: int(count), RetryAfter: window}, nil
+	}
+	return Decision{Allowed: true, Count: int(count)}, nil
+}
+
+func (m *MemcachedStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	item, err := m.client.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return State{Count: 0}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	count, err := strconv.Atoi(string(item.Value))
+	if err != nil {
+		return State{}, err
+	}
+	return State{Count: count}, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// memoryStorageShards controls how many independent locks MemoryStorage
+// spreads its keys across.
+const memoryStorageShards = 32
+
+type memoryShard struct {
+	mu    sync.Mutex
+	calls map[string][]time.Time
+}
+
+// MemoryStorage is the in-process Storage backend. State is sharded across
+// memoryStorageShards maps, each with its own mutex, so that concurrent
+// Acquire calls for different keys don't serialize on one giant lock the way
+// the old single sync.Map of slices did under TestAcquire_HighConcurrency.
+type MemoryStorage struct {
+	shards [memoryStorageShards]*memoryShard
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	m := &MemoryStorage{}
+	for i := range m.shards {
+		m.shards[i] = &memoryShard{calls: make(map[string][]time.Time)}
+	}
+	return m
+}
+
+func (m *MemoryStorage) shardFor(key string) *memoryShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return m.shards[h.Sum32()%memoryStorageShards]
+}
+
+// trim drops entries older than window, keeping the slice's backing array.
+func trim(calls []time.Time, now time.Time, window time.Duration) []time.Time {
+	recent := calls[:0]
+	for _, t := range calls {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+func (m *MemoryStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	shard := m.shardFor(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	calls := trim(shard.calls[key], now, window)
+	if len(calls) >= limit {
+		shard.calls[key] = calls
+		retryAfter := window - now.Sub(calls[0])
+		if retryAfter < 0 {
+			retryAfter = 0
+		}
+		return Decision{Allowed: false, Count: len(calls), RetryAfter: retryAfter}, nil
+	}
+	calls = append(calls, now)
+	shard.calls[key] = calls
+	return Decision{Allowed: true, Count: len(calls)}, nil
+}
+
+func (m *MemoryStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	shard := m.shardFor(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := time.Now()
+	calls := trim(shard.calls[key], now, window)
+	shard.calls[key] = calls
+	return State{Count: len(calls)}, nil
+}
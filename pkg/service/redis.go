@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var errUnexpectedScriptResult = errors.New("ratelimiter: unexpected script result shape")
+
+// redisUniversalClient is the common surface shared by a single-node client,
+// a Sentinel-backed failover client, and a Cluster client, so Service never
+// needs to care which topology it's talking to.
+type redisUniversalClient = redis.UniversalClient
+
+// RedisMode selects the Redis topology the Service talks to.
+type RedisMode string
+
+const (
+	RedisModeSingle   RedisMode = "single"
+	RedisModeSentinel RedisMode = "sentinel"
+	RedisModeCluster  RedisMode = "cluster"
+)
+
+// RedisOptions configures how the Service connects to Redis. It supports a
+// single node, a Sentinel-monitored master/replica set, or a Cluster
+// deployment, selectable via Mode.
+type RedisOptions struct {
+	Mode RedisMode
+
+	// Addr is used in single mode.
+	Addr string
+
+	// SentinelMaster and SentinelAddrs are used in sentinel mode.
+	SentinelMaster string
+	SentinelAddrs  []string
+
+	// ClusterAddrs is used in cluster mode.
+	ClusterAddrs []string
+
+	Password string
+	UseTLS   bool
+}
+
+// RedisOptionsFromEnv builds a RedisOptions from the REDIS_* environment
+// variables, defaulting to a single node at localhost:6379.
+func RedisOptionsFromEnv() RedisOptions {
+	opts := RedisOptions{
+		Mode:           RedisMode(strings.ToLower(os.Getenv("REDIS_MODE"))),
+		Addr:           os.Getenv("REDIS_ADDR"),
+		SentinelMaster: os.Getenv("REDIS_SENTINEL_MASTER"),
+		Password:       os.Getenv("REDIS_PASSWORD"),
+		UseTLS:         os.Getenv("REDIS_TLS") == "true",
+	}
+	if opts.Mode == "" {
+		opts.Mode = RedisModeSingle
+	}
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if addrs := os.Getenv("REDIS_SENTINEL_ADDRS"); addrs != "" {
+		opts.SentinelAddrs = strings.Split(addrs, ",")
+	}
+	if addrs := os.Getenv("REDIS_CLUSTER_ADDRS"); addrs != "" {
+		opts.ClusterAddrs = strings.Split(addrs, ",")
+	}
+	return opts
+}
+
+// newRedisClient builds a redis.UniversalClient for the requested topology.
+// Single and Sentinel both yield a *redis.Client (go-redis models a
+// Sentinel-backed master as a regular client via NewFailoverClient); Cluster
+// yields a *redis.ClusterClient. All three satisfy redis.UniversalClient, so
+// the rest of the package never needs to know which one it got.
+func newRedisClient(opts RedisOptions) redis.UniversalClient {
+	var tlsConfig *tls.Config
+	if opts.UseTLS {
+		tlsConfig = &tls.Config{}
+	}
+
+	switch opts.Mode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.SentinelMaster,
+			SentinelAddrs: opts.SentinelAddrs,
+			Password:      opts.Password,
+			TLSConfig:     tlsConfig,
+		})
+	case RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     opts.ClusterAddrs,
+			Password:  opts.Password,
+			TLSConfig: tlsConfig,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:      opts.Addr,
+			Password:  opts.Password,
+			DB:        0,
+			TLSConfig: tlsConfig,
+		})
+	}
+}
+
+// slidingWindowScript atomically trims, counts, and (if under limit) admits
+// an entry in a sorted-set backed sliding window. It replaces the old
+// ZAdd-then-ZCard pipeline, which admitted a request before checking the
+// count and raced between concurrent clients.
+//
+// KEYS[1] = ratelimit:{key}
+// ARGV[1] = now_ns
+// ARGV[2] = window_ns
+// ARGV[3] = limit
+//
+// Returns {allowed (0/1), count, retry_after_ms}.
+const slidingWindowScript = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', key, now, now .. ':' .. math.random())
+  redis.call('PEXPIRE', key, math.ceil(window / 1e6))
+  allowed = 1
+  count = count + 1
+end
+
+local retry_after_ms = 0
+if allowed == 0 then
+  local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+  if oldest and oldest[2] then
+    local oldest_score = tonumber(oldest[2])
+    retry_after_ms = math.ceil((oldest_score + window - now) / 1e6)
+    if retry_after_ms < 0 then
+      retry_after_ms = 0
+    end
+  end
+end
+
+return {allowed, count, retry_after_ms}
+`
+
+// slidingWindowBulkScript is slidingWindowScript's counterpart for admitting
+// hits entries in one round trip instead of one at a time: CachedStorage
+// uses it to reconcile a batch of already-decided-locally optimistic admits
+// back into the shared window, rather than discarding all but the latest of
+// them. Every hit is recorded unconditionally, since it already happened
+// from the client's point of view; allowed reports whether the window is
+// still within limit afterwards, which is what matters for the next
+// decision.
+//
+// KEYS[1] = ratelimit:{key}
+// ARGV[1] = now_ns
+// ARGV[2] = window_ns
+// ARGV[3] = limit
+// ARGV[4] = hits
+//
+// Returns {allowed (0/1), count, retry_after_ms}.
+const slidingWindowBulkScript = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local hits = tonumber(ARGV[4])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+for i = 1, hits do
+  redis.call('ZADD', key, now, now .. ':' .. i .. ':' .. math.random())
+end
+redis.call('PEXPIRE', key, math.ceil(window / 1e6))
+
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count <= limit then
+  allowed = 1
+end
+
+local retry_after_ms = 0
+if allowed == 0 then
+  local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+  if oldest and oldest[2] then
+    local oldest_score = tonumber(oldest[2])
+    retry_after_ms = math.ceil((oldest_score + window - now) / 1e6)
+    if retry_after_ms < 0 then
+      retry_after_ms = 0
+    end
+  end
+end
+
+return {allowed, count, retry_after_ms}
+`
+
+// slidingWindowCountScript reports the current count for Status without
+// admitting a new entry.
+const slidingWindowCountScript = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+return redis.call('ZCARD', key)
+`
+
+// gcraScript implements the Generic Cell Rate Algorithm against a single
+// string key holding the TAT (theoretical arrival time), in nanoseconds
+// since epoch. Keeping read-check-write inside one script is what lets
+// concurrent instances share a key's TAT without a separate lock.
+//
+// KEYS[1] = gcra:{key}
+// ARGV[1] = now_ns
+// ARGV[2] = emission_interval_ns
+// ARGV[3] = delay_tolerance_ns
+// ARGV[4] = hits
+//
+// Returns {allowed (0/1), retry_after_ms}.
+const gcraScript = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local emission_interval = tonumber(ARGV[2])
+local delay_tolerance = tonumber(ARGV[3])
+local hits = tonumber(ARGV[4]) or 1
+
+local tat = tonumber(redis.call('GET', key))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + emission_interval * hits
+if new_tat - now <= delay_tolerance then
+  redis.call('SET', key, new_tat)
+  redis.call('PEXPIRE', key, math.ceil(delay_tolerance / 1e6))
+  return {1, 0}
+end
+
+local retry_after_ms = math.ceil((new_tat - now - delay_tolerance) / 1e6)
+return {0, retry_after_ms}
+`
+
+// gcraKey hash-tags the key so it stays on a single Cluster slot, matching
+// ratelimitKey's convention for the counter-based algorithms.
+func gcraKey(key string) string {
+	return "gcra:{" + key + "}"
+}
+
+// parseGCRAResult decodes the {allowed, retry_after_ms} tuple returned by
+// gcraScript.
+func parseGCRAResult(res interface{}) (allowed bool, retryAfterMs int64, err error) {
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return false, 0, errUnexpectedScriptResult
+	}
+	allowedInt, ok1 := vals[0].(int64)
+	retryInt, ok2 := vals[1].(int64)
+	if !ok1 || !ok2 {
+		return false, 0, errUnexpectedScriptResult
+	}
+	return allowedInt == 1, retryInt, nil
+}
+
+// scriptRunner loads Lua scripts once and invokes them with EVALSHA,
+// transparently falling back to EVAL when Redis has forgotten the script
+// (NOSCRIPT, e.g. after a restart or FLUSHALL).
+type scriptRunner struct {
+	client redis.UniversalClient
+	script *redis.Script
+}
+
+func newScriptRunner(client redis.UniversalClient, src string) *scriptRunner {
+	return &scriptRunner{client: client, script: redis.NewScript(src)}
+}
+
+func (r *scriptRunner) run(ctx context.Context, keys []string, args ...interface{}) (interface{}, error) {
+	res, err := r.script.Run(ctx, r.client, keys, args...).Result()
+	if err != nil && strings.Contains(err.Error(), "NOSCRIPT") {
+		res, err = r.script.Run(ctx, r.client, keys, args...).Result()
+	}
+	return res, err
+}
+
+// ratelimitKey hash-tags the key so it stays on a single Cluster slot,
+// keeping the Lua script's multi-key-free access pattern valid.
+func ratelimitKey(key string) string {
+	return "ratelimit:{" + key + "}"
+}
+
+// parseSlidingWindowResult decodes the {allowed, count, retry_after_ms}
+// tuple returned by slidingWindowScript.
+func parseSlidingWindowResult(res interface{}) (allowed bool, count int, retryAfterMs int64, err error) {
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 3 {
+		return false, 0, 0, errUnexpectedScriptResult
+	}
+	allowedInt, ok1 := vals[0].(int64)
+	countInt, ok2 := vals[1].(int64)
+	retryInt, ok3 := vals[2].(int64)
+	if !ok1 || !ok2 || !ok3 {
+		return false, 0, 0, errUnexpectedScriptResult
+	}
+	return allowedInt == 1, int(countInt), retryInt, nil
+}
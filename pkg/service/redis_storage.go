@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// RedisStorage is the Storage backend for a Redis-backed sliding window. It
+// delegates the trim/count/admit sequence to slidingWindowScript so it stays
+// atomic regardless of how many clients hit the same key concurrently. It
+// also implements BulkAcquirer, so a CachedStorage wrapping it can reconcile
+// a batch of optimistic local admits in one round trip via
+// slidingWindowBulkScript instead of replaying them one Acquire at a time.
+type RedisStorage struct {
+	client        redisUniversalClient
+	acquireScript *scriptRunner
+	bulkScript    *scriptRunner
+	statusScript  *scriptRunner
+}
+
+func NewRedisStorage(client redisUniversalClient) *RedisStorage {
+	return &RedisStorage{
+		client:        client,
+		acquireScript: newScriptRunner(client, slidingWindowScript),
+		bulkScript:    newScriptRunner(client, slidingWindowBulkScript),
+		statusScript:  newScriptRunner(client, slidingWindowCountScript),
+	}
+}
+
+func (r *RedisStorage) Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	res, err := r.acquireScript.run(ctx, []string{ratelimitKey(key)}, time.Now().UnixNano(), window.Nanoseconds(), limit)
+	if err != nil {
+		return Decision{}, err
+	}
+	allowed, count, retryAfterMs, err := parseSlidingWindowResult(res)
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{
+		Allowed:    allowed,
+		Count:      count,
+		RetryAfter: time.Duration(retryAfterMs) * time.Millisecond,
+	}, nil
+}
+
+// AcquireN records hits already-admitted-locally requests against key in one
+// round trip, for CachedStorage's reconcile path. Every hit is recorded
+// unconditionally (they already happened from the client's point of view);
+// Allowed reports whether the window is still within limit afterwards.
+func (r *RedisStorage) AcquireN(ctx context.Context, key string, hits, limit int, window time.Duration) (Decision, error) {
+	res, err := r.bulkScript.run(ctx, []string{ratelimitKey(key)}, time.Now().UnixNano(), window.Nanoseconds(), limit, hits)
+	if err != nil {
+		return Decision{}, err
+	}
+	allowed, count, retryAfterMs, err := parseSlidingWindowResult(res)
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{
+		Allowed:    allowed,
+		Count:      count,
+		RetryAfter: time.Duration(retryAfterMs) * time.Millisecond,
+	}, nil
+}
+
+func (r *RedisStorage) Status(ctx context.Context, key string, limit int, window time.Duration) (State, error) {
+	res, err := r.statusScript.run(ctx, []string{ratelimitKey(key)}, time.Now().UnixNano(), window.Nanoseconds())
+	if err != nil {
+		return State{}, err
+	}
+	count, ok := res.(int64)
+	if !ok {
+		return State{}, errUnexpectedScriptResult
+	}
+	return State{Count: int(count)}, nil
+}
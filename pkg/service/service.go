@@ -2,36 +2,33 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/redis/go-redis/v9"
-)
-
-type Storage interface {
-	Acquire(ctx context.Context, key string) (bool, error)
-	Status(ctx context.Context, key string) (tokensLeft int, err error)
-}
-
-type RateLimitAlgorithm int
-
-const (
-	TokenBucket RateLimitAlgorithm = iota
-	LeakyBucket
+	"ratelimiter-app/pkg/adminqueue"
 )
 
 type Service struct {
-	mu          sync.Mutex
-	userCalls   sync.Map // concurrent map for user calls
-	limit       int
-	window      time.Duration
-	limits      map[string]int // key: user or API key, value: limit
-	redisClient *redis.Client
-	useRedis    bool
+	mu               sync.Mutex
+	storage          Storage        // backend for TokenBucket, SlidingWindow, FixedWindow
+	localCache       *CachedStorage // set when WithLocalCache wraps storage; nil otherwise
+	gcraStates       sync.Map       // per-key *int64 TAT (nanoseconds since epoch) for the GCRA algorithm
+	gcraScript       *scriptRunner  // GCRA Lua runner, set when useRedis
+	limit            int
+	window           time.Duration
+	limits           map[string]int // key: user or API key, value: limit
+	bursts           map[string]int // key: user or API key, value: GCRA burst (defaults to limit)
+	redisClient      redisUniversalClient
+	useRedis         bool
+
+	configQueue       adminqueue.Queue       // set when WithConfigQueue is used; nil means SetLimit/DeleteLimit apply locally only
+	compactor         *adminqueue.Compactor  // set when WithConfigCompaction is used alongside a Snapshotter-capable configQueue
+	configSnapshotter adminqueue.Snapshotter // set whenever configQueue implements Snapshotter, regardless of compaction; startConfigQueue loads from it before draining
 
 	// Metrics
 	successfulAcquires int64
@@ -42,172 +39,177 @@ type Service struct {
 	algorithm RateLimitAlgorithm
 }
 
-func NewService(algorithm RateLimitAlgorithm) *Service {
-	// Try to connect to Redis
-	addr := os.Getenv("REDIS_ADDR")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+// Option customizes Service construction.
+type Option func(*serviceConfig)
+
+type serviceConfig struct {
+	redisOptions   RedisOptions
+	backend        StorageBackend
+	memcachedAddrs []string
+	storage        Storage
+	localCacheSize int
+	localCacheTTL  time.Duration
+	window         time.Duration
+	configQueue    adminqueue.Queue
+	compactEvery   int
+}
 
-	// Test the connection
-	_, err := rdb.Ping(context.Background()).Result()
-	if err != nil {
-		log.Println("Redis not available, falling back to in-memory storage:", err)
-		return &Service{
-			userCalls: sync.Map{},
-			limit:     5,
-			window:    time.Minute,
-			useRedis:  false,
-			limits:    make(map[string]int),
-			algorithm: algorithm,
-		}
-	}
+// WithRedisOptions overrides the Redis topology the Service connects to when
+// the Redis backend is selected, which otherwise defaults to
+// RedisOptionsFromEnv().
+func WithRedisOptions(opts RedisOptions) Option {
+	return func(c *serviceConfig) { c.redisOptions = opts }
+}
+
+// WithStorageBackend overrides which Storage implementation NewService
+// wires up, which otherwise defaults to storageBackendFromEnv().
+func WithStorageBackend(backend StorageBackend) Option {
+	return func(c *serviceConfig) { c.backend = backend }
+}
+
+// WithStorage injects a ready-made Storage, bypassing backend selection
+// entirely. Useful for tests and for backends (e.g. a cached RedisStorage)
+// that need construction the env-driven defaults don't cover.
+func WithStorage(st Storage) Option {
+	return func(c *serviceConfig) { c.storage = st }
+}
 
-	log.Println("Connected to Redis")
-	return &Service{
-		userCalls:   sync.Map{},
-		limit:       5,
-		window:      time.Minute,
-		redisClient: rdb,
-		useRedis:    true,
-		limits:      make(map[string]int),
-		algorithm:   algorithm,
+// WithWindow overrides the rate-limit window, which otherwise defaults to
+// one minute.
+func WithWindow(window time.Duration) Option {
+	return func(c *serviceConfig) { c.window = window }
+}
+
+// WithLocalCache fronts the selected Storage with an in-process LRU/TTL
+// cache of at most size keys, each cached for up to ttl, so that repeated
+// Acquire calls for an already-exhausted key don't pay a backend round-trip.
+// Intended for RedisStorage and MemcachedStorage, where that round-trip is
+// network latency; layering it over MemoryStorage works but buys nothing.
+func WithLocalCache(size int, ttl time.Duration) Option {
+	return func(c *serviceConfig) {
+		c.localCacheSize = size
+		c.localCacheTTL = ttl
 	}
 }
 
-func (s *Service) Acquire(ctx context.Context, input map[string]interface{}) map[string]interface{} {
-	key, ok := input["key"].(string)
-	if !ok || key == "" {
-		s.mu.Lock()
-		s.failedAcquires++
-		s.mu.Unlock()
-		return map[string]interface{}{
-			"allowed": false,
-			"error":   "missing or invalid key",
-		}
+// WithConfigQueue routes SetLimit/DeleteLimit-equivalent admin changes
+// through q instead of mutating s.limits directly: NewService drains q's
+// backlog and applies it before returning, then keeps applying new ops in
+// the background for the life of the Service. Use a Queue shared across
+// instances (RedisQueue) so they all converge on the same config.
+func WithConfigQueue(q adminqueue.Queue) Option {
+	return func(c *serviceConfig) { c.configQueue = q }
+}
+
+// WithConfigCompaction snapshots the full config every n applied ops, via
+// the configQueue's Snapshotter capability (both LevelDBQueue and
+// RedisQueue implement it). It's a no-op if the configured Queue doesn't
+// implement Snapshotter, or if WithConfigQueue wasn't used at all.
+func WithConfigCompaction(n int) Option {
+	return func(c *serviceConfig) { c.compactEvery = n }
+}
+
+func NewService(algorithm RateLimitAlgorithm, opts ...Option) *Service {
+	cfg := serviceConfig{
+		redisOptions:   RedisOptionsFromEnv(),
+		backend:        storageBackendFromEnv(),
+		memcachedAddrs: memcachedAddrsFromEnv(),
+		window:         time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
 
-	switch s.algorithm {
-	case TokenBucket:
-		return s.acquireTokenBucket(ctx, key)
-	case LeakyBucket:
-		return s.acquireLeakyBucket(ctx, key)
-	default:
-		return map[string]interface{}{
-			"allowed": false,
-			"error":   "unknown algorithm",
-		}
+	svc := &Service{
+		limit:     5,
+		window:    cfg.window,
+		limits:    make(map[string]int),
+		bursts:    make(map[string]int),
+		algorithm: algorithm,
 	}
-}
 
-func (s *Service) acquireTokenBucket(ctx context.Context, key string) map[string]interface{} {
-	if s.useRedis && s.redisClient != nil {
-		redisKey := "ratelimit:" + key
-		now := float64(time.Now().UnixNano()) / 1e9
-		windowStart := now - s.window.Seconds()
-		limit := s.getLimitForKey(key)
-
-		pipe := s.redisClient.TxPipeline()
-		pipe.ZRemRangeByScore(ctx, redisKey, "0", fmt.Sprintf("%f", windowStart))
-		pipe.ZAdd(ctx, redisKey, redis.Z{Score: now, Member: fmt.Sprintf("%f", now)})
-		zcard := pipe.ZCard(ctx, redisKey)
-		pipe.Expire(ctx, redisKey, s.window)
-		_, err := pipe.Exec(ctx)
-		if err != nil {
-			s.failedAcquires++
-			return map[string]interface{}{
-				"allowed": false,
-				"error":   "redis error",
-			}
-		}
-		count, err := zcard.Result()
-		if err != nil {
-			s.failedAcquires++
-			return map[string]interface{}{
-				"allowed": false,
-				"error":   "redis error",
-			}
-		}
-		if int(count) > limit { // <-- should be > limit, not >=
-			s.failedAcquires++
-			return map[string]interface{}{
-				"allowed": false,
-				"error":   "rate limit exceeded",
-			}
-		}
-		s.successfulAcquires++
-		return map[string]interface{}{
-			"allowed": true,
+	switch {
+	case cfg.storage != nil:
+		svc.storage = cfg.storage
+	case cfg.backend == StorageBackendMemory:
+		svc.storage = NewMemoryStorage()
+	case cfg.backend == StorageBackendMemcached:
+		svc.storage = NewMemcachedStorage(cfg.memcachedAddrs...)
+	default: // StorageBackendRedis or StorageBackendAuto
+		rdb := newRedisClient(cfg.redisOptions)
+		if _, err := rdb.Ping(context.Background()).Result(); err != nil {
+			log.Println("Redis not available, falling back to in-memory storage:", err)
+			svc.storage = NewMemoryStorage()
+			break
 		}
+		log.Println("Connected to Redis")
+		svc.redisClient = rdb
+		svc.useRedis = true
+		svc.storage = NewRedisStorage(rdb)
+		svc.gcraScript = newScriptRunner(rdb, gcraScript)
 	}
 
-	now := time.Now()
-	limit := s.getLimitForKey(key)
+	if algorithm == GCRA && cfg.backend == StorageBackendMemcached {
+		log.Println("GCRA does not use the Memcached backend (it keeps its own per-key state, shared only when Redis is selected); this instance's GCRA state will not be shared with other instances")
+	}
 
-	val, _ := s.userCalls.LoadOrStore(key, []time.Time{})
-	calls, _ := val.([]time.Time)
+	if cfg.localCacheSize > 0 {
+		svc.localCache = NewCachedStorage(svc.storage, cfg.localCacheSize, cfg.localCacheTTL)
+		svc.storage = svc.localCache
+	}
 
-	// Remove calls outside the window
-	var recentCalls []time.Time
-	for _, t := range calls {
-		if now.Sub(t) < s.window {
-			recentCalls = append(recentCalls, t)
+	if cfg.configQueue != nil {
+		svc.configQueue = cfg.configQueue
+		if snap, ok := cfg.configQueue.(adminqueue.Snapshotter); ok {
+			svc.configSnapshotter = snap
+			if cfg.compactEvery > 0 {
+				svc.compactor = adminqueue.NewCompactor(snap, cfg.compactEvery, svc.configSnapshot)
+				go svc.compactor.Run(context.Background())
+			}
 		}
+		svc.startConfigQueue()
 	}
-	if len(recentCalls) >= limit {
-		s.failedAcquires++
+	return svc
+}
+
+// Acquire decides whether key may make one more request, consuming hits
+// units of its quota instead of one (e.g. a bytes-consumed counter sent as
+// a descriptor's hits_addend) when hits is given. hits defaults to 1. GCRA
+// and Storage backends that implement BulkAcquirer decide all hits in a
+// single call; on other backends (MemoryStorage, MemcachedStorage) hits are
+// applied as sequential Acquire calls that stop at the first denial, so a
+// denied request may already have consumed part of its hits.
+func (s *Service) Acquire(ctx context.Context, input map[string]interface{}, hits ...int) map[string]interface{} {
+	key, ok := input["key"].(string)
+	if !ok || key == "" {
+		atomic.AddInt64(&s.failedAcquires, 1)
 		return map[string]interface{}{
 			"allowed": false,
-			"error":   "rate limit exceeded",
+			"error":   "missing or invalid key",
 		}
 	}
-	recentCalls = append(recentCalls, now)
-	s.userCalls.Store(key, recentCalls)
-	s.successfulAcquires++
-	return map[string]interface{}{
-		"allowed": true,
+	n := 1
+	if len(hits) > 0 && hits[0] > 0 {
+		n = hits[0]
 	}
-}
 
-func (s *Service) acquireLeakyBucket(ctx context.Context, key string) map[string]interface{} {
-	now := time.Now()
 	limit := s.getLimitForKey(key)
-	interval := s.window / time.Duration(limit)
-
-	val, _ := s.userCalls.LoadOrStore(key, []time.Time{})
-	calls, _ := val.([]time.Time)
-
-	// Remove calls outside the window
-	var recentCalls []time.Time
-	for _, t := range calls {
-		if now.Sub(t) < s.window {
-			recentCalls = append(recentCalls, t)
-		}
-	}
-	// Allow if enough time has passed since the last allowed request
-	if len(recentCalls) == 0 || now.Sub(recentCalls[len(recentCalls)-1]) >= interval {
-		recentCalls = append(recentCalls, now)
-		s.userCalls.Store(key, recentCalls)
-		s.successfulAcquires++
+	switch s.algorithm {
+	case TokenBucket, SlidingWindow:
+		return s.acquireViaStorage(ctx, key, limit, n)
+	case FixedWindow:
+		return s.acquireViaStorage(ctx, fixedWindowKey(key, s.window), limit, n)
+	case GCRA:
+		return s.acquireGCRA(ctx, key, n)
+	default:
+		atomic.AddInt64(&s.failedAcquires, 1)
 		return map[string]interface{}{
-			"allowed": true,
+			"allowed": false,
+			"error":   "unknown algorithm",
 		}
 	}
-	s.failedAcquires++
-	return map[string]interface{}{
-		"allowed": false,
-		"error":   "leaky bucket: rate limit exceeded",
-	}
 }
 
-// Sliding window algorithm in Redis
-// (acquireRedis is currently unused and retryRedis is not needed, so both can be removed to fix the compile error)
-
 func (s *Service) CreateExampleData(inputData map[string]interface{}) map[string]interface{} {
 	return map[string]interface{}{
 		"status": "created",
@@ -223,67 +225,17 @@ func (s *Service) GetExampleData() map[string]interface{} {
 
 func (s *Service) Status(ctx context.Context, key string) map[string]interface{} {
 	limit := s.getLimitForKey(key)
-	if s.useRedis && s.redisClient != nil {
-		tokensLeft, err := s.statusRedis(ctx, key)
-		if err == nil {
-			return map[string]interface{}{
-				"key":         key,
-				"tokens_left": tokensLeft,
-				"limit":       limit,
-				"window_sec":  int(s.window.Seconds()),
-				"refill_rate": float64(limit) / s.window.Seconds(),
-				"source":      "redis",
-			}
-		}
-	}
 
-	now := time.Now()
-	val, _ := s.userCalls.Load(key)
-	var calls []time.Time
-	if val != nil {
-		calls, _ = val.([]time.Time)
-	}
-	var recentCalls []time.Time
-	for _, t := range calls {
-		if now.Sub(t) < s.window {
-			recentCalls = append(recentCalls, t)
-		}
-	}
-	tokensLeft := limit - len(recentCalls)
-	if tokensLeft < 0 {
-		tokensLeft = 0
-	}
-	return map[string]interface{}{
-		"key":         key,
-		"tokens_left": tokensLeft,
-		"limit":       limit,
-		"window_sec":  int(s.window.Seconds()),
-		"refill_rate": float64(limit) / s.window.Seconds(),
-	}
-}
-
-func (s *Service) statusRedis(ctx context.Context, key string) (int, error) {
-	redisKey := "ratelimit:" + key
-	now := float64(time.Now().UnixNano()) / 1e9
-	windowStart := now - s.window.Seconds()
-	limit := s.getLimitForKey(key)
-
-	pipe := s.redisClient.TxPipeline()
-	pipe.ZRemRangeByScore(ctx, redisKey, "0", fmt.Sprintf("%f", windowStart))
-	zcard := pipe.ZCard(ctx, redisKey)
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return 0, err
-	}
-	count, err := zcard.Result()
-	if err != nil {
-		return 0, err
-	}
-	tokensLeft := limit - int(count)
-	if tokensLeft < 0 {
-		tokensLeft = 0
+	switch s.algorithm {
+	case GCRA:
+		return s.statusGCRA(ctx, key, limit)
+	case FixedWindow:
+		result := s.statusViaStorage(ctx, fixedWindowKey(key, s.window), limit)
+		result["key"] = key
+		return result
+	default:
+		return s.statusViaStorage(ctx, key, limit)
 	}
-	return tokensLeft, nil
 }
 
 // ExampleMethod is a placeholder for a business logic method
@@ -296,14 +248,12 @@ func (s *Service) ExampleMethod(input string) string {
 
 // Add a method to get metrics in Prometheus format
 func (s *Service) Metrics() string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return `# HELP ratelimiter_successful_acquires Number of successful acquire attempts
+	metrics := `# HELP ratelimiter_successful_acquires Number of successful acquire attempts
 # TYPE ratelimiter_successful_acquires counter
-ratelimiter_successful_acquires ` + itoa(s.successfulAcquires) + `
+ratelimiter_successful_acquires ` + itoa(atomic.LoadInt64(&s.successfulAcquires)) + `
 # HELP ratelimiter_failed_acquires Number of failed acquire attempts
 # TYPE ratelimiter_failed_acquires counter
-ratelimiter_failed_acquires ` + itoa(s.failedAcquires) + `
+ratelimiter_failed_acquires ` + itoa(atomic.LoadInt64(&s.failedAcquires)) + `
 # HELP ratelimiter_requests_last_second Requests in the last second
 # TYPE ratelimiter_requests_last_second gauge
 ratelimiter_requests_last_second ` + itoa(s.requestsLastSecond) + `
@@ -314,9 +264,48 @@ ratelimiter_redis_latency_microseconds ` + itoa(s.redisLatencyMicros) + `
 # TYPE ratelimiter_goroutines gauge
 ratelimiter_goroutines ` + itoa(int64(runtime.NumGoroutine())) + `
 `
+	if s.localCache != nil {
+		hits, misses := s.localCache.hitRatio()
+		metrics += `# HELP ratelimiter_local_cache_hits Number of Acquire calls served from the local cache
+# TYPE ratelimiter_local_cache_hits counter
+ratelimiter_local_cache_hits ` + itoa(hits) + `
+# HELP ratelimiter_local_cache_misses Number of Acquire calls that missed the local cache
+# TYPE ratelimiter_local_cache_misses counter
+ratelimiter_local_cache_misses ` + itoa(misses) + `
+`
+	}
+	return metrics
 }
 
-func (s *Service) SetLimit(key string, limit int) {
+// SetLimit sets key's limit, and for the GCRA algorithm, its optional burst
+// capacity (the number of requests a key may make back-to-back before it's
+// throttled back to the steady limit/window rate). burst defaults to limit
+// when omitted, so existing two-argument call sites are unaffected.
+func (s *Service) SetLimit(key string, limit int, burst ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limits == nil {
+		s.limits = make(map[string]int)
+	}
+	s.limits[key] = limit
+	if s.bursts == nil {
+		s.bursts = make(map[string]int)
+	}
+	if len(burst) > 0 {
+		s.bursts[key] = burst[0]
+	} else {
+		delete(s.bursts, key)
+	}
+}
+
+// SetLimitKeepingBurst sets key's limit without touching any burst override
+// already configured for it, unlike SetLimit's bare two-argument form (which
+// clears the override back to "no burst"). Use it for call sites that only
+// ever have a limit to apply - e.g. re-asserting an API key's configured
+// limit on every request - where clearing a burst set separately (through
+// the admin API) would be a surprising side effect rather than an intended
+// change.
+func (s *Service) SetLimitKeepingBurst(key string, limit int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.limits == nil {
@@ -334,6 +323,204 @@ func (s *Service) getLimitForKey(key string) int {
 	return s.limit // global default
 }
 
+// getBurstForKey returns key's configured GCRA burst, defaulting to its
+// limit (i.e. no extra burst allowance beyond the steady rate).
+func (s *Service) getBurstForKey(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.bursts[key]; ok {
+		return b
+	}
+	if l, ok := s.limits[key]; ok {
+		return l
+	}
+	return s.limit
+}
+
+// EnqueueSetLimit durably records a limit (and optional burst) change. With
+// a configQueue set, the change is pushed there and only takes effect once
+// it comes back around through applyConfigOp, same as it would on every
+// other instance sharing that queue; with no configQueue it falls back to
+// calling SetLimit directly, so the method works the same in the common
+// single-instance deployment.
+func (s *Service) EnqueueSetLimit(ctx context.Context, key string, limit, burst int) error {
+	if s.configQueue == nil {
+		if burst > 0 {
+			s.SetLimit(key, limit, burst)
+		} else {
+			s.SetLimit(key, limit)
+		}
+		return nil
+	}
+	return s.configQueue.Push(ctx, adminqueue.Op{
+		Type:  adminqueue.OpSetLimit,
+		Key:   key,
+		Limit: limit,
+		Burst: burst,
+	})
+}
+
+// EnqueueDeleteLimit durably records that key's limit override should be
+// removed, falling back to deleting it locally when no configQueue is set.
+func (s *Service) EnqueueDeleteLimit(ctx context.Context, key string) error {
+	if s.configQueue == nil {
+		s.mu.Lock()
+		delete(s.limits, key)
+		delete(s.bursts, key)
+		s.mu.Unlock()
+		return nil
+	}
+	return s.configQueue.Push(ctx, adminqueue.Op{Type: adminqueue.OpDeleteLimit, Key: key})
+}
+
+// LimitConfig reports key's currently effective limit and burst, and
+// whether it has an override at all (false means both values are just the
+// Service-wide default).
+func (s *Service) LimitConfig(key string) (limit, burst int, overridden bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.limits[key]
+	if !ok {
+		return s.limit, s.limit, false
+	}
+	b, hasBurst := s.bursts[key]
+	if !hasBurst {
+		b = l
+	}
+	return l, b, true
+}
+
+// startConfigQueue loads the most recent Compactor snapshot (if any), then
+// replays whatever's already queued in s.configQueue on top of it, then
+// spawns the goroutine that keeps applying new ops for the life of s. Since
+// applied ops are Acked (and so removed from the queue) as they're replayed,
+// the snapshot - not the queue - is what carries limits/bursts/algorithm
+// across a restart; without it, a restart would only see ops still
+// in-flight since the last Ack. It's only called from NewService, once, when
+// a configQueue was configured.
+func (s *Service) startConfigQueue() {
+	ctx := context.Background()
+	if s.configSnapshotter != nil {
+		data, ok, err := s.configSnapshotter.LoadSnapshot(ctx)
+		if err != nil {
+			log.Println("adminqueue: load snapshot failed:", err)
+		} else if ok {
+			if err := s.applyConfigSnapshot(data); err != nil {
+				log.Println("adminqueue: apply snapshot failed:", err)
+			}
+		}
+	}
+	ops, err := s.configQueue.Drain(ctx)
+	if err != nil {
+		log.Println("adminqueue: drain failed:", err)
+	}
+	for _, op := range ops {
+		s.applyConfigOp(op)
+		if err := s.configQueue.Ack(ctx, op); err != nil {
+			log.Println("adminqueue: ack failed:", err)
+		}
+	}
+	go s.watchConfigQueue(ctx)
+}
+
+// watchConfigQueue applies ops from s.configQueue as they arrive, forever.
+// It only returns when ctx is cancelled, which in practice is never for the
+// background.Context() startConfigQueue hands it - Service has no Close
+// today, matching how the gRPC and SIGHUP watchers in this codebase are
+// also left running for the process lifetime.
+func (s *Service) watchConfigQueue(ctx context.Context) {
+	for {
+		op, err := s.configQueue.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("adminqueue: next failed:", err)
+			continue
+		}
+		s.applyConfigOp(op)
+		if err := s.configQueue.Ack(ctx, op); err != nil {
+			log.Println("adminqueue: ack failed:", err)
+		}
+	}
+}
+
+func (s *Service) applyConfigOp(op adminqueue.Op) {
+	switch op.Type {
+	case adminqueue.OpSetLimit, adminqueue.OpOnboardTenant:
+		if op.Burst > 0 {
+			s.SetLimit(op.Key, op.Limit, op.Burst)
+		} else {
+			s.SetLimit(op.Key, op.Limit)
+		}
+	case adminqueue.OpDeleteLimit:
+		s.mu.Lock()
+		delete(s.limits, op.Key)
+		delete(s.bursts, op.Key)
+		s.mu.Unlock()
+	case adminqueue.OpSetAlgorithm:
+		if alg, ok := algorithmFromString(op.Algorithm); ok {
+			s.mu.Lock()
+			s.algorithm = alg
+			s.mu.Unlock()
+		}
+	}
+	if s.compactor != nil {
+		s.compactor.Tick()
+	}
+}
+
+// configSnapshotData is the JSON shape configSnapshot writes and
+// applyConfigSnapshot reads back: the full set of limit/burst overrides plus
+// the active algorithm, enough to reconstruct s.limits/s.bursts/s.algorithm
+// without replaying every op ever queued.
+type configSnapshotData struct {
+	Limits    map[string]int `json:"limits"`
+	Bursts    map[string]int `json:"bursts"`
+	Algorithm string         `json:"algorithm"`
+}
+
+// configSnapshot is the adminqueue.SnapshotFunc a Compactor calls.
+func (s *Service) configSnapshot() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limits := make(map[string]int, len(s.limits))
+	for k, v := range s.limits {
+		limits[k] = v
+	}
+	bursts := make(map[string]int, len(s.bursts))
+	for k, v := range s.bursts {
+		bursts[k] = v
+	}
+	return configSnapshotData{
+		Limits:    limits,
+		Bursts:    bursts,
+		Algorithm: s.algorithm.String(),
+	}
+}
+
+// applyConfigSnapshot restores s.limits/s.bursts/s.algorithm from data, as
+// produced by configSnapshot and persisted via a Snapshotter. Called once
+// from startConfigQueue, before any queued ops are replayed on top of it.
+func (s *Service) applyConfigSnapshot(data []byte) error {
+	var snap configSnapshotData
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap.Limits != nil {
+		s.limits = snap.Limits
+	}
+	if snap.Bursts != nil {
+		s.bursts = snap.Bursts
+	}
+	if alg, ok := algorithmFromString(snap.Algorithm); ok {
+		s.algorithm = alg
+	}
+	return nil
+}
+
 // Helper to convert int64 to string (no strconv for simplicity)
 func itoa(i int64) string {
 	return fmt.Sprintf("%d", i)
@@ -10,7 +10,7 @@ import (
 
 func TestAcquire_HighConcurrency(t *testing.T) {
 	s := &Service{
-		userCalls: sync.Map{},
+		storage: NewMemoryStorage(),
 		limit:     1000,
 		window:    time.Second,
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,13 +27,13 @@ func setupRedis(t *testing.T) *Service {
 	// Clean up keys before test
 	rdb.FlushDB(ctx)
 	return &Service{
-		userCalls:   sync.Map{},
 		limit:       2,
 		window:      time.Second,
 		redisClient: rdb,
 		useRedis:    true,
+		storage:     NewRedisStorage(rdb),
 		limits:      make(map[string]int),
-		algorithm:   TokenBucket, // or LeakyBucket if you want to test that
+		algorithm:   TokenBucket, // or GCRA if you want to test that
 	}
 }
 
@@ -86,3 +87,32 @@ func TestRedisStatus_TokensLeft(t *testing.T) {
 		t.Errorf("expected tokens_left=1, got %v", tokens)
 	}
 }
+
+// TestRedisAcquire_NoOverAdmissionUnderConcurrency hammers the same key from
+// many goroutines at once to prove the Lua script admits exactly `limit`
+// requests, unlike the old ZAdd-before-ZCard pipeline which could race.
+func TestRedisAcquire_NoOverAdmissionUnderConcurrency(t *testing.T) {
+	s := setupRedis(t)
+	s.limit = 50
+	ctx := context.Background()
+	input := map[string]interface{}{"key": "redisuser-concurrency"}
+
+	var wg sync.WaitGroup
+	var allowed int64
+	concurrency := 500
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := s.Acquire(ctx, input)
+			if ok, _ := res["allowed"].(bool); ok {
+				atomic.AddInt64(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != int64(s.limit) {
+		t.Errorf("expected exactly %d admissions, got %d", s.limit, allowed)
+	}
+}
@@ -2,14 +2,13 @@ package service
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 )
 
 func TestInMemoryAcquire_Allowed(t *testing.T) {
 	s := &Service{
-		userCalls: sync.Map{},
+		storage: NewMemoryStorage(),
 		limit:     2,
 		window:    time.Second,
 	}
@@ -32,7 +31,7 @@ func TestInMemoryAcquire_Allowed(t *testing.T) {
 
 func TestInMemoryAcquire_RateLimited(t *testing.T) {
 	s := &Service{
-		userCalls: sync.Map{},
+		storage: NewMemoryStorage(),
 		limit:     1,
 		window:    time.Second,
 	}
@@ -58,7 +57,7 @@ func TestInMemoryAcquire_RateLimited(t *testing.T) {
 
 func TestStatus_TokensLeft(t *testing.T) {
 	s := &Service{
-		userCalls: sync.Map{},
+		storage: NewMemoryStorage(),
 		limit:     3,
 		window:    time.Second,
 	}
@@ -77,9 +76,31 @@ func TestStatus_TokensLeft(t *testing.T) {
 	}
 }
 
+func TestSetLimitKeepingBurst_PreservesExistingBurst(t *testing.T) {
+	s := &Service{
+		storage: NewMemoryStorage(),
+		limit:   5,
+		window:  time.Second,
+	}
+
+	s.SetLimit("user4", 10, 20)
+	s.SetLimitKeepingBurst("user4", 15)
+
+	limit, burst, overridden := s.LimitConfig("user4")
+	if !overridden {
+		t.Fatalf("expected user4 to still have an override")
+	}
+	if limit != 15 {
+		t.Errorf("expected limit=15, got %d", limit)
+	}
+	if burst != 20 {
+		t.Errorf("expected burst to be left untouched at 20, got %d", burst)
+	}
+}
+
 func TestAcquire_MissingKey(t *testing.T) {
 	s := &Service{
-		userCalls: sync.Map{},
+		storage: NewMemoryStorage(),
 		limit:     1,
 		window:    time.Second,
 	}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+)
+
+// Decision is the outcome of a Storage.Acquire call.
+type Decision struct {
+	Allowed    bool
+	Count      int
+	RetryAfter time.Duration
+}
+
+// State is the outcome of a Storage.Status call: a read-only snapshot of a
+// key's current usage, without consuming a slot.
+type State struct {
+	Count int
+}
+
+// Storage is the backend a rate-limit algorithm counts against. Each backend
+// (MemoryStorage, RedisStorage, MemcachedStorage) implements it once;
+// TokenBucket, SlidingWindow and FixedWindow in algorithms.go are pure
+// functions of key/limit/window over a Storage, so any of those can be
+// paired with any backend. GCRA is the exception: it keeps its own per-key
+// TAT state outside the Storage interface (an in-process sync.Map, or,
+// when the Redis backend is selected, a Lua script against the same Redis
+// client), so a Memcached backend never actually backs it - see NewService's
+// warning for that combination.
+type Storage interface {
+	Acquire(ctx context.Context, key string, limit int, window time.Duration) (Decision, error)
+	Status(ctx context.Context, key string, limit int, window time.Duration) (State, error)
+}
+
+// BulkAcquirer is implemented by Storage backends that can apply hits
+// already-decided requests in a single round trip, instead of replaying
+// them one Acquire call at a time. CachedStorage uses it, when the wrapped
+// Storage implements it, to push its accumulated optimistic local admits
+// back during reconcile; acquireHits uses it to honor a descriptor's
+// hits_addend (pkg/ratelimitgrpc) in one call instead of one per hit.
+type BulkAcquirer interface {
+	AcquireN(ctx context.Context, key string, hits, limit int, window time.Duration) (Decision, error)
+}
+
+// acquireHits applies hits requests to storage: a single BulkAcquirer call
+// when storage supports one and hits calls for it, a plain Acquire for the
+// common single-hit case, and otherwise hits sequential Acquire calls,
+// stopping at the first denial (every call after that would just be
+// re-confirming it).
+func acquireHits(ctx context.Context, storage Storage, key string, hits, limit int, window time.Duration) (Decision, error) {
+	if hits <= 1 {
+		return storage.Acquire(ctx, key, limit, window)
+	}
+	if bulk, ok := storage.(BulkAcquirer); ok {
+		return bulk.AcquireN(ctx, key, hits, limit, window)
+	}
+
+	var dec Decision
+	var err error
+	for i := 0; i < hits; i++ {
+		dec, err = storage.Acquire(ctx, key, limit, window)
+		if err != nil || !dec.Allowed {
+			break
+		}
+	}
+	return dec, err
+}
+
+// StorageBackend selects which Storage implementation NewService wires up.
+type StorageBackend string
+
+const (
+	// StorageBackendAuto tries Redis and falls back to MemoryStorage if it
+	// isn't reachable, matching the package's historical default.
+	StorageBackendAuto      StorageBackend = ""
+	StorageBackendMemory    StorageBackend = "memory"
+	StorageBackendRedis     StorageBackend = "redis"
+	StorageBackendMemcached StorageBackend = "memcached"
+)
+
+func storageBackendFromEnv() StorageBackend {
+	return StorageBackend(strings.ToLower(os.Getenv("STORAGE_BACKEND")))
+}